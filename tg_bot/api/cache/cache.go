@@ -0,0 +1,209 @@
+// Package cache кэширует ответы api.Finder: сначала в памяти процесса, затем, если задано, в storage.CacheStore
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// Config время жизни записей; NegativeTTL — для поисков без результатов, они устаревают быстрее,
+// потому что игру могут добавить в базу в любой момент.
+// Namespace отделяет записи разных источников в общем store: id игр IGDB и RAWG пересекаются
+type Config struct {
+	Namespace   string
+	Size        int
+	SearchTTL   time.Duration
+	GameTTL     time.Duration
+	NegativeTTL time.Duration
+}
+
+// Finder api.Finder с кэшем. Ошибки, кроме api.ErrNoSearchResults, не кэшируются
+type Finder struct {
+	next   api.Finder
+	memory *lru
+	//store необязателен, без него кэш живёт до перезапуска
+	store  storage.CacheStore
+	config Config
+	now    func() time.Time
+
+	hits   atomic.Int64
+	misses atomic.Int64
+
+	mu         sync.Mutex
+	lastPruned time.Time
+}
+
+// Stats число запросов, отданных из кэша и ушедших в api.Finder
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+// notFound ответ без результатов, хранится как null
+var notFound = []byte("null")
+
+// pruneInterval как часто удалять из store устаревшие записи; читать их и так не будут, но место они занимают
+const pruneInterval = time.Hour
+
+func New(next api.Finder, store storage.CacheStore, config Config) *Finder {
+	return &Finder{
+		next:   next,
+		memory: newLRU(config.Size),
+		store:  store,
+		config: config,
+		now:    time.Now,
+	}
+}
+
+func (f *Finder) Stats() Stats {
+	return Stats{
+		Hits:   f.hits.Load(),
+		Misses: f.misses.Load(),
+	}
+}
+
+func (f *Finder) Source() storage.Source {
+	return f.next.Source()
+}
+
+func (f *Finder) Find(ctx context.Context, name string) (res []api.SearchResult, err error) {
+	defer func() { err = e.WrapIfNil("can't find game list with cache", err) }()
+
+	//Поиск в IGDB не различает регистр и лишние пробелы
+	key := f.config.Namespace + "search:" + strings.ToLower(strings.Join(strings.Fields(name), " "))
+
+	if data, ok := f.get(ctx, key); ok {
+		if err := json.Unmarshal(data, &res); err == nil {
+			if res == nil {
+				return nil, api.ErrNoSearchResults
+			}
+			return res, nil
+		}
+	}
+
+	res, err = f.next.Find(ctx, name)
+	if err != nil {
+		return nil, f.saveError(ctx, key, err)
+	}
+
+	f.save(ctx, key, res, f.config.SearchTTL)
+
+	return res, nil
+}
+
+func (f *Finder) FindGameById(ctx context.Context, gameId int) (res *api.Game, err error) {
+	defer func() { err = e.WrapIfNil("can't find one game data with cache", err) }()
+
+	key := f.config.Namespace + "game:" + strconv.Itoa(gameId)
+
+	if data, ok := f.get(ctx, key); ok {
+		if err := json.Unmarshal(data, &res); err == nil {
+			if res == nil {
+				return nil, api.ErrNoSearchResults
+			}
+			return res, nil
+		}
+	}
+
+	res, err = f.next.FindGameById(ctx, gameId)
+	if err != nil {
+		return nil, f.saveError(ctx, key, err)
+	}
+
+	f.save(ctx, key, res, f.config.GameTTL)
+
+	return res, nil
+}
+
+// get ищет запись в памяти, затем в store. Сбой store не мешает поиску: запрос просто уйдёт в api.Finder
+func (f *Finder) get(ctx context.Context, key string) ([]byte, bool) {
+	now := f.now()
+
+	if data, ok := f.memory.get(key, now); ok {
+		f.hits.Add(1)
+		return data, true
+	}
+
+	if f.store != nil {
+		entry, err := f.store.GetCache(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrNoCache) {
+			log.Print("[ERR] ", err)
+		}
+		if err == nil && now.Before(entry.ExpiresAt) {
+			f.memory.set(key, entry.Value, entry.ExpiresAt)
+			f.hits.Add(1)
+			return entry.Value, true
+		}
+	}
+
+	f.misses.Add(1)
+
+	return nil, false
+}
+
+// saveError запоминает отсутствие результатов и возвращает err без изменений
+func (f *Finder) saveError(ctx context.Context, key string, err error) error {
+	if errors.Is(err, api.ErrNoSearchResults) {
+		f.set(ctx, key, notFound, f.config.NegativeTTL)
+	}
+
+	return err
+}
+
+func (f *Finder) save(ctx context.Context, key string, value any, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Print("[ERR] can't marshal cache entry: ", err)
+		return
+	}
+
+	f.set(ctx, key, data, ttl)
+}
+
+func (f *Finder) set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	expiresAt := f.now().Add(ttl)
+
+	f.memory.set(key, data, expiresAt)
+
+	if f.store == nil {
+		return
+	}
+
+	entry := &storage.CacheEntry{
+		Key:       key,
+		Value:     data,
+		ExpiresAt: expiresAt,
+	}
+	if err := f.store.SaveCache(ctx, entry); err != nil {
+		log.Print("[ERR] ", err)
+	}
+
+	f.prune(ctx)
+}
+
+// prune не чаще раза в pruneInterval удаляет из store устаревшие записи
+func (f *Finder) prune(ctx context.Context) {
+	now := f.now()
+
+	f.mu.Lock()
+	if now.Sub(f.lastPruned) < pruneInterval {
+		f.mu.Unlock()
+		return
+	}
+	f.lastPruned = now
+	f.mu.Unlock()
+
+	if err := f.store.RemoveCacheBefore(ctx, now); err != nil {
+		log.Print("[ERR] ", err)
+	}
+}
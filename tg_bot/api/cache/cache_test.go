@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/sqlite"
+	"time"
+)
+
+// countingFinder знает одну игру и считает обращения
+type countingFinder struct {
+	calls int
+}
+
+func (f *countingFinder) Find(ctx context.Context, name string) ([]api.SearchResult, error) {
+	f.calls++
+	if name != "Half-Life 2" {
+		return nil, api.ErrNoSearchResults
+	}
+
+	return []api.SearchResult{{Id: 233, Name: "Half-Life 2", FirstReleaseDate: time.Date(2004, 11, 16, 0, 0, 0, 0, time.UTC)}}, nil
+}
+
+func (f *countingFinder) Source() storage.Source {
+	return storage.Igdb
+}
+
+func (f *countingFinder) FindGameById(ctx context.Context, gameId int) (*api.Game, error) {
+	f.calls++
+	if gameId != 233 {
+		return nil, errors.New("igdb is down")
+	}
+
+	return &api.Game{Id: 233, Name: "Half-Life 2", Genres: []string{"Shooter"}}, nil
+}
+
+var testConfig = Config{
+	Size:        2,
+	SearchTTL:   time.Hour,
+	GameTTL:     24 * time.Hour,
+	NegativeTTL: time.Minute,
+}
+
+func TestFinder(t *testing.T) {
+	ctx := context.Background()
+	next := &countingFinder{}
+	f := New(next, nil, testConfig)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	for _, name := range []string{"Half-Life 2", "  half-life   2 "} {
+		res, err := f.Find(ctx, name)
+		if err != nil || len(res) != 1 || res[0].Id != 233 || !res[0].FirstReleaseDate.Equal(time.Date(2004, 11, 16, 0, 0, 0, 0, time.UTC)) {
+			t.Fatalf("Find(%q) = %+v, %v", name, res, err)
+		}
+	}
+
+	for range 2 {
+		game, err := f.FindGameById(ctx, 233)
+		if err != nil || game.Name != "Half-Life 2" || len(game.Genres) != 1 {
+			t.Fatalf("FindGameById = %+v, %v", game, err)
+		}
+	}
+
+	if next.calls != 2 {
+		t.Fatalf("finder called %d times, want once per query", next.calls)
+	}
+	if got := f.Stats(); got != (Stats{Hits: 2, Misses: 2}) {
+		t.Fatalf("Stats = %+v, want 2 hits and 2 misses", got)
+	}
+
+	//Поиск устаревает раньше данных игры
+	now = now.Add(2 * time.Hour)
+	_, _ = f.Find(ctx, "Half-Life 2")
+	_, _ = f.FindGameById(ctx, 233)
+	if next.calls != 3 {
+		t.Fatalf("finder called %d times, want only expired search to be refetched", next.calls)
+	}
+}
+
+func TestFinderNegative(t *testing.T) {
+	ctx := context.Background()
+	next := &countingFinder{}
+	f := New(next, nil, testConfig)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := f.Find(ctx, "Half-Life 3"); !errors.Is(err, api.ErrNoSearchResults) {
+			t.Fatalf("Find = %v, want ErrNoSearchResults", err)
+		}
+	}
+	if next.calls != 1 {
+		t.Fatalf("finder called %d times, want empty result to be cached", next.calls)
+	}
+
+	now = now.Add(testConfig.NegativeTTL)
+	_, _ = f.Find(ctx, "Half-Life 3")
+	if next.calls != 2 {
+		t.Fatalf("finder called %d times, want empty result to expire", next.calls)
+	}
+
+	//Прочие ошибки не кэшируются
+	for range 2 {
+		if _, err := f.FindGameById(ctx, 1); err == nil {
+			t.Fatal("FindGameById: want error")
+		}
+	}
+	if next.calls != 4 {
+		t.Fatalf("finder called %d times, want errors to be retried", next.calls)
+	}
+}
+
+func TestFinderEviction(t *testing.T) {
+	ctx := context.Background()
+	next := &countingFinder{}
+	f := New(next, nil, testConfig)
+
+	_, _ = f.FindGameById(ctx, 233)
+	_, _ = f.Find(ctx, "Half-Life 2")
+	_, _ = f.FindGameById(ctx, 233)
+	//В кэше на две записи третья вытесняет поиск, к которому обращались раньше всего
+	_, _ = f.Find(ctx, "Half-Life 3")
+	_, _ = f.FindGameById(ctx, 233)
+	_, _ = f.Find(ctx, "Half-Life 2")
+
+	if next.calls != 4 {
+		t.Fatalf("finder called %d times, want least recently used entry to be evicted", next.calls)
+	}
+}
+
+func TestFinderStore(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("can't open storage: %v", err)
+	}
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("can't init storage: %v", err)
+	}
+
+	next := &countingFinder{}
+	if _, err := New(next, s, testConfig).FindGameById(ctx, 233); err != nil {
+		t.Fatalf("FindGameById: %v", err)
+	}
+	if _, err := New(next, s, testConfig).Find(ctx, "Half-Life 3"); !errors.Is(err, api.ErrNoSearchResults) {
+		t.Fatalf("Find = %v, want ErrNoSearchResults", err)
+	}
+
+	//После перезапуска память пуста, ответы берутся из хранилища
+	restarted := New(next, s, testConfig)
+	if game, err := restarted.FindGameById(ctx, 233); err != nil || game.Name != "Half-Life 2" {
+		t.Fatalf("FindGameById after restart = %+v, %v", game, err)
+	}
+	if _, err := restarted.Find(ctx, "Half-Life 3"); !errors.Is(err, api.ErrNoSearchResults) {
+		t.Fatalf("Find after restart = %v, want ErrNoSearchResults", err)
+	}
+	if next.calls != 2 {
+		t.Fatalf("finder called %d times, want restarted cache to use storage", next.calls)
+	}
+
+	//Записи другого источника с теми же id не видны
+	other := testConfig
+	other.Namespace = "rawg:"
+	if _, err := New(next, s, other).FindGameById(ctx, 233); err != nil {
+		t.Fatalf("FindGameById in other namespace: %v", err)
+	}
+	if next.calls != 3 {
+		t.Fatalf("finder called %d times, want namespaces to be separate", next.calls)
+	}
+}
+
+func TestFinderStorePrune(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("can't open storage: %v", err)
+	}
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("can't init storage: %v", err)
+	}
+
+	f := New(&countingFinder{}, s, testConfig)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+
+	_, _ = f.Find(ctx, "Half-Life 3")
+
+	//Запись устарела, но удаляется только при следующей записи после pruneInterval
+	now = now.Add(pruneInterval)
+	_, _ = f.Find(ctx, "Half-Life 2")
+
+	if _, err := s.GetCache(ctx, "search:half-life 3"); !errors.Is(err, storage.ErrNoCache) {
+		t.Fatalf("GetCache for expired entry = %v, want ErrNoCache", err)
+	}
+	if _, err := s.GetCache(ctx, "search:half-life 2"); err != nil {
+		t.Fatalf("GetCache for fresh entry: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package apicalypse собирает запросы на языке Apicalypse, на котором работает IGDB.
+// Пользовательские значения всегда экранируются, поэтому название игры не может изменить запрос
+package apicalypse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Order string
+
+const (
+	Asc  Order = "asc"
+	Desc Order = "desc"
+)
+
+var (
+	ErrArgsCount       = errors.New("placeholders and arguments count mismatch")
+	ErrUnsupportedType = errors.New("unsupported argument type")
+)
+
+// Query запрос Apicalypse; пустые части в запрос не попадают
+type Query struct {
+	search string
+	fields []string
+	where  []string
+	sort   string
+	limit  int
+	offset int
+	err    error
+}
+
+func New() *Query {
+	return &Query{}
+}
+
+// Search полнотекстовый поиск по названию
+func (q *Query) Search(text string) *Query {
+	q.search = text
+	return q
+}
+
+func (q *Query) Fields(fields ...string) *Query {
+	q.fields = append(q.fields, fields...)
+	return q
+}
+
+// Where добавляет условие, несколько условий объединяются через &.
+// Каждый ? в condition заменяется следующим экранированным аргументом: строки в кавычках, числа как есть,
+// nil как null, срезы чисел как (1,2,3). ? внутри аргументов не раскрываются
+func (q *Query) Where(condition string, args ...any) *Query {
+	if q.err != nil {
+		return q
+	}
+
+	var builder strings.Builder
+
+	parts := strings.Split(condition, "?")
+	if len(parts)-1 != len(args) {
+		q.err = fmt.Errorf("%w: %q with %d arguments", ErrArgsCount, condition, len(args))
+		return q
+	}
+
+	builder.WriteString(parts[0])
+	for i, arg := range args {
+		value, err := literal(arg)
+		if err != nil {
+			q.err = err
+			return q
+		}
+		builder.WriteString(value)
+		builder.WriteString(parts[i+1])
+	}
+
+	q.where = append(q.where, builder.String())
+	return q
+}
+
+func (q *Query) Sort(field string, order Order) *Query {
+	q.sort = field + " " + string(order)
+	return q
+}
+
+func (q *Query) Limit(limit int) *Query {
+	q.limit = limit
+	return q
+}
+
+func (q *Query) Offset(offset int) *Query {
+	q.offset = offset
+	return q
+}
+
+// Build возвращает текст запроса или первую ошибку, допущенную при его сборке
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+
+	var clauses []string
+
+	if q.search != "" {
+		clauses = append(clauses, "search "+Quote(q.search)+";")
+	}
+	if len(q.fields) > 0 {
+		clauses = append(clauses, "fields "+strings.Join(q.fields, ",")+";")
+	}
+	if len(q.where) > 0 {
+		clauses = append(clauses, "where "+strings.Join(q.where, " & ")+";")
+	}
+	if q.sort != "" {
+		clauses = append(clauses, "sort "+q.sort+";")
+	}
+	if q.limit > 0 {
+		clauses = append(clauses, "limit "+strconv.Itoa(q.limit)+";")
+	}
+	if q.offset > 0 {
+		clauses = append(clauses, "offset "+strconv.Itoa(q.offset)+";")
+	}
+
+	return strings.Join(clauses, " "), nil
+}
+
+var quoteReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	//Перевод строки в строке запроса IGDB не принимает, для поиска он ничего не значит
+	"\n", " ",
+	"\r", " ",
+)
+
+// Quote строка Apicalypse в двойных кавычках
+func Quote(s string) string {
+	return `"` + quoteReplacer.Replace(s) + `"`
+}
+
+func literal(arg any) (string, error) {
+	switch v := arg.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return Quote(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []int:
+		values := make([]string, 0, len(v))
+		for _, n := range v {
+			values = append(values, strconv.Itoa(n))
+		}
+		return "(" + strings.Join(values, ",") + ")", nil
+	}
+
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedType, arg)
+}
@@ -0,0 +1,65 @@
+package apicalypse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{
+			name: "search",
+			query: New().
+				Search(`Half-Life 2: "Episode" ?`).
+				Fields("id", "name").
+				Where("version_parent = ?", nil).
+				Where("game_type = ?", 0).
+				Limit(50),
+			want: `search "Half-Life 2: \"Episode\" ?"; fields id,name; where version_parent = null & game_type = 0; limit 50;`,
+		},
+		{
+			name:  "injection",
+			query: New().Search(`x"; fields *; where id = 1; limit 500; search "`),
+			want:  `search "x\"; fields *; where id = 1; limit 500; search \"";`,
+		},
+		{
+			name:  "backslash",
+			query: New().Search(`a\" b` + "\nc"),
+			want:  `search "a\\\" b c";`,
+		},
+		{
+			name: "where with string",
+			query: New().
+				Fields("id").
+				Where("name = ? & id = ?", `Half-Life 2: "Episode" ?`, 233).
+				Where("platforms = ?", []int{6, 48}).
+				Sort("first_release_date", Desc).
+				Limit(10).
+				Offset(20),
+			want: `fields id; where name = "Half-Life 2: \"Episode\" ?" & id = 233 & platforms = (6,48); sort first_release_date desc; limit 10; offset 20;`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.query.Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Build =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := New().Where("id = ? & name = ?", 1).Build(); !errors.Is(err, ErrArgsCount) {
+		t.Fatalf("Build with missing argument = %v, want ErrArgsCount", err)
+	}
+	if _, err := New().Where("id = ?", 1.5).Build(); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("Build with float argument = %v, want ErrUnsupportedType", err)
+	}
+}
@@ -0,0 +1,199 @@
+package igdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"tg_game_wishlist/lib/e"
+	"time"
+)
+
+// TwitchTokenURL адрес выдачи токенов Twitch, через который авторизуется IGDB
+const TwitchTokenURL = "https://id.twitch.tv/oauth2/token"
+
+// Токен обновляется заранее, чтобы запрос не ушёл с истекающим токеном
+const refreshBefore = time.Hour
+
+// Authorizer выдаёт значение заголовка Authorization для запросов к IGDB
+type Authorizer interface {
+	Authorization(ctx context.Context) (string, error)
+	// Invalidate сбрасывает токен, который IGDB отклонил с 401
+	Invalidate()
+}
+
+// StaticToken заранее выданный токен, обновить его нельзя
+type StaticToken struct {
+	authorization string
+}
+
+func NewStaticToken(tokenType, token string) *StaticToken {
+	return &StaticToken{authorization: newAuthorization(tokenType, token)}
+}
+
+func (s *StaticToken) Authorization(ctx context.Context) (string, error) {
+	return s.authorization, nil
+}
+
+func (s *StaticToken) Invalidate() {}
+
+// Token токен приложения и время, до которого он действует
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ClientCredentials получает токен приложения по client_id и client_secret (client credentials flow Twitch)
+// и обновляет его перед истечением. Если задан cachePath, токен сохраняется в файл и переживает перезапуск
+type ClientCredentials struct {
+	endpoint     string
+	clientId     string
+	clientSecret string
+	cachePath    string
+	client       http.Client
+
+	mu    sync.Mutex
+	token *Token
+	now   func() time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+func NewClientCredentials(endpoint, clientId, clientSecret, cachePath string) *ClientCredentials {
+	return &ClientCredentials{
+		endpoint:     endpoint,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		cachePath:    cachePath,
+		client: http.Client{
+			Timeout: 30 * time.Second,
+		},
+		now: time.Now,
+	}
+}
+
+func (c *ClientCredentials) Authorization(ctx context.Context) (res string, err error) {
+	defer func() { err = e.WrapIfNil("can't get authorization", err) }()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == nil {
+		c.token = c.loadCache()
+	}
+
+	if c.token == nil || c.token.ExpiresAt.Sub(c.now()) < refreshBefore {
+		token, err := c.requestToken(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.token = token
+		c.saveCache()
+	}
+
+	return newAuthorization(c.token.TokenType, c.token.AccessToken), nil
+}
+
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = nil
+	if c.cachePath != "" {
+		_ = os.Remove(c.cachePath)
+	}
+}
+
+func (c *ClientCredentials) requestToken(ctx context.Context) (token *Token, err error) {
+	defer func() { err = e.WrapIfNil("can't request token", err) }()
+
+	q := url.Values{}
+	q.Add("client_id", c.clientId)
+	q.Add("client_secret", c.clientSecret)
+	q.Add("grant_type", "client_credentials")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(q.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var res tokenResponse
+
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, err
+	}
+	if res.AccessToken == "" {
+		return nil, errors.New("empty access token")
+	}
+
+	return &Token{
+		AccessToken: res.AccessToken,
+		TokenType:   res.TokenType,
+		ExpiresAt:   c.now().Add(time.Duration(res.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// loadCache читает токен из файла; испорченный или отсутствующий файл означает, что токена нет
+func (c *ClientCredentials) loadCache() *Token {
+	if c.cachePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.cachePath)
+	if err != nil {
+		return nil
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" {
+		return nil
+	}
+
+	return &token
+}
+
+// saveCache не возвращает ошибку: без файла бот работает, токен просто запросится заново после перезапуска
+func (c *ClientCredentials) saveCache() {
+	if c.cachePath == "" {
+		return
+	}
+
+	data, err := json.Marshal(c.token)
+	if err == nil {
+		err = os.WriteFile(c.cachePath, data, 0600)
+	}
+	//WriteFile задаёт права только новому файлу, а в файле секрет, поэтому права старого тоже сужаются
+	if err == nil {
+		err = os.Chmod(c.cachePath, 0600)
+	}
+	if err != nil {
+		log.Print("[ERR] can't cache igdb token: ", err)
+	}
+}
@@ -0,0 +1,147 @@
+package igdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// tokenStub выдаёт токены token-1, token-2, ... со сроком expiresIn секунд
+func tokenStub(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("can't parse form: %v", err)
+		}
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "id" || r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		n := issued.Add(1)
+		_, _ = fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":%d,"token_type":"bearer"}`, n, expiresIn)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	ctx := context.Background()
+	srv, issued := tokenStub(t, 3600*24)
+
+	auth := NewClientCredentials(srv.URL, "id", "secret", "")
+	now := time.Now()
+	auth.now = func() time.Time { return now }
+
+	for range 2 {
+		got, err := auth.Authorization(ctx)
+		if err != nil {
+			t.Fatalf("Authorization: %v", err)
+		}
+		if got != "Bearer token-1" {
+			t.Fatalf("Authorization = %q, want %q", got, "Bearer token-1")
+		}
+	}
+	if issued.Load() != 1 {
+		t.Fatalf("issued %d tokens, want the cached one to be reused", issued.Load())
+	}
+
+	//Незадолго до истечения токен обновляется
+	now = now.Add(24*time.Hour - refreshBefore/2)
+	if got, _ := auth.Authorization(ctx); got != "Bearer token-2" {
+		t.Fatalf("Authorization before expiry = %q, want refreshed token", got)
+	}
+
+	auth.Invalidate()
+	if got, _ := auth.Authorization(ctx); got != "Bearer token-3" {
+		t.Fatalf("Authorization after Invalidate = %q, want new token", got)
+	}
+}
+
+func TestClientCredentialsCache(t *testing.T) {
+	ctx := context.Background()
+	srv, issued := tokenStub(t, 3600*24)
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	if _, err := NewClientCredentials(srv.URL, "id", "secret", path).Authorization(ctx); err != nil {
+		t.Fatalf("Authorization: %v", err)
+	}
+
+	//После перезапуска токен берётся из файла
+	got, err := NewClientCredentials(srv.URL, "id", "secret", path).Authorization(ctx)
+	if err != nil {
+		t.Fatalf("Authorization from cache: %v", err)
+	}
+	if got != "Bearer token-1" || issued.Load() != 1 {
+		t.Fatalf("Authorization = %q after %d requests, want cached token-1", got, issued.Load())
+	}
+}
+
+// В файле секрет: доступ к нему должен быть только у владельца, даже если файл уже был создан с другими правами
+func TestClientCredentialsCacheMode(t *testing.T) {
+	srv, _ := tokenStub(t, 3600*24)
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatalf("can't create cache file: %v", err)
+	}
+
+	if _, err := NewClientCredentials(srv.URL, "id", "secret", path).Authorization(context.Background()); err != nil {
+		t.Fatalf("Authorization: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("can't stat cache file: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Fatalf("cache file mode = %o, want 600", mode)
+	}
+}
+
+func TestClientCredentialsError(t *testing.T) {
+	srv, _ := tokenStub(t, 3600)
+
+	if _, err := NewClientCredentials(srv.URL, "id", "wrong", "").Authorization(context.Background()); err == nil {
+		t.Fatal("Authorization with wrong secret: want error")
+	}
+}
+
+// IGDB отклоняет токен до его истечения (например, после смены secret): запрос повторяется с новым токеном
+func TestFinderRetriesOnUnauthorized(t *testing.T) {
+	tokens, issued := tokenStub(t, 3600*24)
+
+	var requests atomic.Int32
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Client-ID") != "id" || r.Header.Get("Authorization") != "Bearer token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"Authorization Failure"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":233,"name":"Half-Life 2"}]`))
+	}))
+	t.Cleanup(api.Close)
+
+	f := New(strings.TrimPrefix(api.URL, "https://"), "id", NewClientCredentials(tokens.URL, "id", "secret", ""))
+	f.client = *api.Client()
+
+	res, err := f.Find(context.Background(), "half-life")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(res) != 1 || res[0].Id != 233 {
+		t.Fatalf("Find = %+v, want Half-Life 2", res)
+	}
+	if requests.Load() != 2 || issued.Load() != 2 {
+		t.Fatalf("made %d requests with %d tokens, want one retry with a new token", requests.Load(), issued.Load())
+	}
+}
@@ -0,0 +1,55 @@
+package igdb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrUnauthorized IGDB отклонил токен
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("too many requests")
+	ErrBadRequest   = errors.New("bad request")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError ответ IGDB с кодом не из 2xx, сравнивается через errors.Is с ErrRateLimited и другими
+type APIError struct {
+	Code int
+	Body string
+	kind error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("igdb api error %d: %s", e.Code, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func newAPIError(code int, body []byte) *APIError {
+	err := &APIError{
+		Code: code,
+		Body: string(body),
+	}
+
+	switch {
+	case code == http.StatusUnauthorized:
+		err.kind = ErrUnauthorized
+	case code == http.StatusTooManyRequests:
+		err.kind = ErrRateLimited
+	case code >= http.StatusInternalServerError:
+		err.kind = ErrServer
+	case code >= http.StatusBadRequest:
+		err.kind = ErrBadRequest
+	}
+
+	return err
+}
+
+// retryable ошибки, которые проходят сами: превышение лимита и сбои на стороне IGDB
+func retryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServer)
+}
@@ -3,39 +3,70 @@ package igdb
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
-	"strconv"
 	"strings"
 	"tg_game_wishlist/api"
+	"tg_game_wishlist/api/igdb/apicalypse"
 	"tg_game_wishlist/lib/e"
 	"tg_game_wishlist/storage"
+	"time"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
+	"golang.org/x/time/rate"
 )
 
+// Finder ищет игры в IGDB. Все запросы проходят через общий лимит частоты и числа одновременных запросов
 type Finder struct {
-	host          string
-	clientId      string
-	authorization string
-	client        http.Client
+	host     string
+	clientId string
+	auth     Authorizer
+	client   http.Client
+	limiter  *rate.Limiter
+	//Свободные места для одновременных запросов
+	slots chan struct{}
+	//Пауза перед первым повтором, дальше удваивается
+	backoff time.Duration
 }
 
 const (
-	gamesMethod    = "v4/games"
-	gamesListParam = "search \"?\"; fields id,name,first_release_date; where version_parent = null & game_type = 0; limit 50;"
-	gameParam      = "fields id,name,url,release_dates.date,release_dates.platform.abbreviation; where id = ?;"
+	gamesMethod = "v4/games"
+	searchLimit = 50
+	//game_type = 0 — основные игры, без DLC и сборников
+	mainGame = 0
+	//Размер обложки cover_big — 264x374
+	coverURL = "https://images.igdb.com/igdb/image/upload/t_cover_big/%s.jpg"
+
+	//Лимиты IGDB: 4 запроса в секунду и не больше 8 открытых запросов
+	requestsPerSecond = 4
+	maxOpenRequests   = 8
+	maxRetries        = 3
+	retryBackoff      = 500 * time.Millisecond
 )
 
-func New(host, clientId, tokenType, token string) *Finder {
+var (
+	gamesListFields = []string{"id", "name", "url", "first_release_date"}
+	gameFields      = []string{
+		"id", "name", "url", "summary", "cover.image_id", "genres.name",
+		"involved_companies.developer", "involved_companies.company.name",
+		"release_dates.date", "release_dates.platform.abbreviation",
+	}
+)
+
+func New(host, clientId string, auth Authorizer) *Finder {
 	return &Finder{
-		host:          host,
-		clientId:      clientId,
-		authorization: newAuthorization(tokenType, token),
-		client:        http.Client{},
+		host:     host,
+		clientId: clientId,
+		auth:     auth,
+		client:   http.Client{},
+		limiter:  rate.NewLimiter(requestsPerSecond, requestsPerSecond),
+		slots:    make(chan struct{}, maxOpenRequests),
+		backoff:  retryBackoff,
 	}
 }
 
@@ -45,10 +76,23 @@ func newAuthorization(tokenType, token string) string {
 	return authCaser.String(tokenType) + " " + token
 }
 
+func (f *Finder) Source() storage.Source {
+	return storage.Igdb
+}
+
 func (f *Finder) Find(ctx context.Context, name string) (res []api.SearchResult, err error) {
 	defer func() { err = e.WrapIfNil("can't find game list", err) }()
 
-	reqBody := strings.ReplaceAll(gamesListParam, "?", name)
+	reqBody, err := apicalypse.New().
+		Search(name).
+		Fields(gamesListFields...).
+		Where("version_parent = ?", nil).
+		Where("game_type = ?", mainGame).
+		Limit(searchLimit).
+		Build()
+	if err != nil {
+		return nil, err
+	}
 	log.Print(reqBody)
 
 	data, err := f.doRequest(ctx, gamesMethod, nil, reqBody)
@@ -78,6 +122,7 @@ func searchResult(game Game) api.SearchResult {
 	var res api.SearchResult
 	res.Id = game.Id
 	res.Name = game.Name
+	res.URL = game.URL
 	res.FirstReleaseDate = game.FirstReleaseDate.Time
 
 	return res
@@ -86,7 +131,13 @@ func searchResult(game Game) api.SearchResult {
 func (f *Finder) FindGameById(ctx context.Context, gameId int) (res *api.Game, err error) {
 	defer func() { err = e.WrapIfNil("can't find one game data", err) }()
 
-	reqBody := strings.ReplaceAll(gameParam, "?", strconv.Itoa(gameId))
+	reqBody, err := apicalypse.New().
+		Fields(gameFields...).
+		Where("id = ?", gameId).
+		Build()
+	if err != nil {
+		return nil, err
+	}
 	log.Print(reqBody)
 
 	data, err := f.doRequest(ctx, gamesMethod, nil, reqBody)
@@ -99,6 +150,9 @@ func (f *Finder) FindGameById(ctx context.Context, gameId int) (res *api.Game, e
 	if err := json.Unmarshal(data, &response); err != nil {
 		return nil, err
 	}
+	if len(response) == 0 {
+		return nil, api.ErrNoSearchResults
+	}
 
 	return game(response[0]), nil
 }
@@ -110,12 +164,29 @@ func game(response Game) *api.Game {
 		URL:          response.URL,
 		ReleaseDates: make([]api.PlatformDate, 0, len(response.ReleaseDates)),
 		Source:       storage.Igdb,
+		Summary:      response.Summary,
 	}
 
 	for _, rDate := range response.ReleaseDates {
 		res.ReleaseDates = append(res.ReleaseDates, releaseDate(rDate))
 	}
 
+	if response.Cover != nil && response.Cover.ImageId != "" {
+		res.CoverURL = fmt.Sprintf(coverURL, response.Cover.ImageId)
+	}
+
+	for _, genre := range response.Genres {
+		res.Genres = append(res.Genres, genre.Name)
+	}
+
+	var developers []string
+	for _, ic := range response.InvolvedCompanies {
+		if ic.Developer {
+			developers = append(developers, ic.Company.Name)
+		}
+	}
+	res.Developer = strings.Join(developers, ", ")
+
 	return res
 }
 
@@ -133,9 +204,52 @@ func platform(date ReleaseDate) api.Platform {
 	}
 }
 
+// doRequest при 401 запрашивает новый токен и повторяет запрос один раз,
+// при 429 и 5xx повторяет запрос до maxRetries раз с растущей паузой
 func (f *Finder) doRequest(ctx context.Context, method string, q url.Values, reqBody string) (data []byte, err error) {
 	defer func() { err = e.WrapIfNil("can't do request", err) }()
 
+	reauthorized := false
+	for attempt := 0; ; attempt++ {
+		data, err = f.doRequestOnce(ctx, method, q, reqBody)
+
+		if errors.Is(err, ErrUnauthorized) && !reauthorized {
+			f.auth.Invalidate()
+			reauthorized = true
+			continue
+		}
+		if !retryable(err) || attempt >= maxRetries {
+			return data, err
+		}
+
+		backoff := f.backoff << attempt
+		log.Printf("igdb request to %s failed, retry after %s: %s", method, backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (f *Finder) doRequestOnce(ctx context.Context, method string, q url.Values, reqBody string) ([]byte, error) {
+	if err := f.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	select {
+	case f.slots <- struct{}{}:
+		defer func() { <-f.slots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	authorization, err := f.auth.Authorization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	u := url.URL{
 		Scheme: "https",
 		Host:   f.host,
@@ -150,7 +264,7 @@ func (f *Finder) doRequest(ctx context.Context, method string, q url.Values, req
 
 	req.Header.Set("Content-Type", "text/plain")
 	req.Header.Set("Client-ID", f.clientId)
-	req.Header.Set("Authorization", f.authorization)
+	req.Header.Set("Authorization", authorization)
 
 	req.URL.RawQuery = q.Encode()
 
@@ -165,6 +279,9 @@ func (f *Finder) doRequest(ctx context.Context, method string, q url.Values, req
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, newAPIError(resp.StatusCode, body)
+	}
 
 	return body, nil
 }
@@ -0,0 +1,135 @@
+package igdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// newTestFinder Finder, который ходит в handler вместо IGDB, без лимита частоты и с короткими паузами между повторами
+func newTestFinder(t *testing.T, handler http.HandlerFunc) *Finder {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	f := New(strings.TrimPrefix(srv.URL, "https://"), "id", NewStaticToken("bearer", "token"))
+	f.client = *srv.Client()
+	f.limiter = rate.NewLimiter(rate.Inf, 0)
+	f.backoff = time.Millisecond
+
+	return f
+}
+
+func TestFinderRetriesRateLimit(t *testing.T) {
+	var requests atomic.Int32
+	f := newTestFinder(t, func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id":233,"name":"Half-Life 2"}]`))
+	})
+
+	res, err := f.Find(context.Background(), "half-life")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(res) != 1 || requests.Load() != 3 {
+		t.Fatalf("Find = %+v after %d requests, want result after two retries", res, requests.Load())
+	}
+}
+
+func TestFinderErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		code     int
+		want     error
+		requests int32
+	}{
+		{name: "server error", code: http.StatusBadGateway, want: ErrServer, requests: maxRetries + 1},
+		{name: "rate limited", code: http.StatusTooManyRequests, want: ErrRateLimited, requests: maxRetries + 1},
+		{name: "bad request", code: http.StatusBadRequest, want: ErrBadRequest, requests: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests atomic.Int32
+			f := newTestFinder(t, func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tc.code)
+				_, _ = w.Write([]byte(`{"title":"Syntax Error"}`))
+			})
+
+			_, err := f.FindGameById(context.Background(), 233)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || !errors.Is(err, tc.want) || apiErr.Code != tc.code {
+				t.Fatalf("FindGameById = %v, want APIError %d (%v)", err, tc.code, tc.want)
+			}
+			if requests.Load() != tc.requests {
+				t.Fatalf("made %d requests, want %d", requests.Load(), tc.requests)
+			}
+		})
+	}
+}
+
+func TestFinderLimitsOpenRequests(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		open    int
+		maxOpen int
+	)
+	f := newTestFinder(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		open++
+		maxOpen = max(maxOpen, open)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		open--
+		mu.Unlock()
+
+		_, _ = w.Write([]byte(`[{"id":233,"name":"Half-Life 2"}]`))
+	})
+
+	var wg sync.WaitGroup
+	for range 3 * maxOpenRequests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Find(context.Background(), "half-life"); err != nil {
+				t.Errorf("Find: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxOpen > maxOpenRequests {
+		t.Fatalf("%d requests were open at once, want at most %d", maxOpen, maxOpenRequests)
+	}
+}
+
+func TestFinderRateLimit(t *testing.T) {
+	f := newTestFinder(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":233,"name":"Half-Life 2"}]`))
+	})
+	f.limiter = rate.NewLimiter(requestsPerSecond, requestsPerSecond)
+
+	//Первые requestsPerSecond запросов проходят сразу, следующий ждёт пополнения
+	start := time.Now()
+	for range requestsPerSecond + 1 {
+		if _, err := f.Find(context.Background(), "half-life"); err != nil {
+			t.Fatalf("Find: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < time.Second/requestsPerSecond/2 {
+		t.Fatalf("%d requests took %s, want limiter to delay the last one", requestsPerSecond+1, elapsed)
+	}
+}
@@ -14,6 +14,28 @@ type Game struct {
 	URL              string        `json:"url"`
 	FirstReleaseDate UnixTime      `json:"first_release_date,omitempty"`
 	ReleaseDates     []ReleaseDate `json:"release_dates"`
+	Summary          string        `json:"summary"`
+	Cover            *Cover        `json:"cover"`
+	Genres           []Genre       `json:"genres"`
+	// InvolvedCompanies все компании, причастные к игре; разработчики отмечены флагом Developer
+	InvolvedCompanies []InvolvedCompany `json:"involved_companies"`
+}
+
+type Cover struct {
+	ImageId string `json:"image_id"`
+}
+
+type Genre struct {
+	Name string `json:"name"`
+}
+
+type InvolvedCompany struct {
+	Developer bool    `json:"developer"`
+	Company   Company `json:"company"`
+}
+
+type Company struct {
+	Name string `json:"name"`
 }
 
 type ReleaseDate struct {
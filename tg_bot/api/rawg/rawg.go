@@ -0,0 +1,183 @@
+package rawg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+)
+
+// Finder ищет игры в RAWG, запасной источник на случай, когда нет доступа к IGDB
+type Finder struct {
+	host   string
+	key    string
+	client http.Client
+}
+
+const (
+	gamesMethod = "api/games"
+	searchLimit = 40
+	//Ссылка на страницу игры на сайте, API её не возвращает
+	gameURL = "https://rawg.io/games/%s"
+)
+
+func New(host, key string) *Finder {
+	return &Finder{
+		host:   host,
+		key:    key,
+		client: http.Client{},
+	}
+}
+
+func (f *Finder) Source() storage.Source {
+	return storage.Rawg
+}
+
+func (f *Finder) Find(ctx context.Context, name string) (res []api.SearchResult, err error) {
+	defer func() { err = e.WrapIfNil("can't find game list", err) }()
+
+	q := url.Values{}
+	q.Add("search", name)
+	q.Add("page_size", strconv.Itoa(searchLimit))
+
+	data, err := f.doRequest(ctx, gamesMethod, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var response SearchResponse
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Results) == 0 {
+		return nil, api.ErrNoSearchResults
+	}
+
+	res = make([]api.SearchResult, 0, len(response.Results))
+
+	for _, game := range response.Results {
+		res = append(res, searchResult(game))
+	}
+
+	return res, nil
+}
+
+func searchResult(game Game) api.SearchResult {
+	return api.SearchResult{
+		Id:               game.Id,
+		Name:             game.Name,
+		URL:              fmt.Sprintf(gameURL, game.Slug),
+		FirstReleaseDate: game.Released.Time,
+	}
+}
+
+func (f *Finder) FindGameById(ctx context.Context, gameId int) (res *api.Game, err error) {
+	defer func() { err = e.WrapIfNil("can't find one game data", err) }()
+
+	data, err := f.doRequest(ctx, path.Join(gamesMethod, strconv.Itoa(gameId)), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var response Game
+
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, err
+	}
+
+	return game(response), nil
+}
+
+func game(response Game) *api.Game {
+	res := &api.Game{
+		Id:           response.Id,
+		Name:         response.Name,
+		URL:          fmt.Sprintf(gameURL, response.Slug),
+		ReleaseDates: make([]api.PlatformDate, 0, len(response.Platforms)),
+		Source:       storage.Rawg,
+		CoverURL:     response.BackgroundImage,
+		Summary:      response.DescriptionRaw,
+	}
+
+	for _, p := range response.Platforms {
+		res.ReleaseDates = append(res.ReleaseDates, releaseDate(p, response.Released))
+	}
+
+	for _, genre := range response.Genres {
+		res.Genres = append(res.Genres, genre.Name)
+	}
+
+	developers := make([]string, 0, len(response.Developers))
+	for _, developer := range response.Developers {
+		developers = append(developers, developer.Name)
+	}
+	res.Developer = strings.Join(developers, ", ")
+
+	return res
+}
+
+// releaseDate дата выхода на платформе; если RAWG её не знает, берётся общая дата выхода игры
+func releaseDate(p PlatformRelease, released Date) api.PlatformDate {
+	date := p.ReleasedAt.Time
+	if date.IsZero() {
+		date = released.Time
+	}
+
+	return api.PlatformDate{
+		Platform: api.Platform{
+			Id:   p.Platform.Id,
+			Name: p.Platform.Name,
+		},
+		Date: date,
+	}
+}
+
+func (f *Finder) doRequest(ctx context.Context, method string, q url.Values) (data []byte, err error) {
+	defer func() { err = e.WrapIfNil("can't do request", err) }()
+
+	u := url.URL{
+		Scheme: "https",
+		Host:   f.host,
+		Path:   method,
+	}
+
+	//Ключ добавляется после логирования, чтобы не попасть в лог
+	log.Print(u.String() + "?" + q.Encode())
+	q.Set("key", f.key)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, api.ErrNoSearchResults
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("rawg api error %d: %s", resp.StatusCode, body)
+	}
+
+	return body, nil
+}
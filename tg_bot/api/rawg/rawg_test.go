@@ -0,0 +1,139 @@
+package rawg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+const searchResponse = `{
+	"count": 2,
+	"results": [
+		{"id": 13537, "slug": "half-life-2", "name": "Half-Life 2", "released": "2004-11-16",
+		 "platforms": [{"platform": {"id": 4, "name": "PC"}, "released_at": null}]},
+		{"id": 999, "slug": "half-life-3", "name": "Half-Life 3", "released": null}
+	]
+}`
+
+const gameResponse = `{
+	"id": 13537,
+	"slug": "half-life-2",
+	"name": "Half-Life 2",
+	"released": "2004-11-16",
+	"description_raw": "Gordon Freeman wakes up on a train.",
+	"background_image": "https://media.rawg.io/media/games/hl2.jpg",
+	"genres": [{"name": "Shooter"}, {"name": "Action"}],
+	"developers": [{"name": "Valve Software"}],
+	"platforms": [
+		{"platform": {"id": 4, "name": "PC"}, "released_at": "2004-11-16"},
+		{"platform": {"id": 14, "name": "Xbox 360"}, "released_at": "2007-10-10"},
+		{"platform": {"id": 1, "name": "Xbox One"}, "released_at": null}
+	]
+}`
+
+func newTestFinder(t *testing.T) *Finder {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case r.URL.Path == "/api/games" && r.URL.Query().Get("search") == `Half-Life 2: "Episode" ?`:
+			_, _ = w.Write([]byte(searchResponse))
+		case r.URL.Path == "/api/games":
+			_, _ = w.Write([]byte(`{"count": 0, "results": []}`))
+		case r.URL.Path == "/api/games/13537":
+			_, _ = w.Write([]byte(gameResponse))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"detail": "Not found."}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	f := New(strings.TrimPrefix(srv.URL, "https://"), "secret")
+	f.client = *srv.Client()
+
+	return f
+}
+
+func TestFind(t *testing.T) {
+	f := newTestFinder(t)
+
+	res, err := f.Find(context.Background(), `Half-Life 2: "Episode" ?`)
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("Find returned %d results, want 2", len(res))
+	}
+
+	want := api.SearchResult{
+		Id:               13537,
+		Name:             "Half-Life 2",
+		URL:              "https://rawg.io/games/half-life-2",
+		FirstReleaseDate: time.Date(2004, 11, 16, 0, 0, 0, 0, time.UTC),
+	}
+	if res[0] != want {
+		t.Fatalf("Find()[0] = %+v, want %+v", res[0], want)
+	}
+	if !res[1].FirstReleaseDate.IsZero() {
+		t.Fatalf("Find()[1] release date = %s, want unknown", res[1].FirstReleaseDate)
+	}
+
+	if _, err := f.Find(context.Background(), "nothing"); !errors.Is(err, api.ErrNoSearchResults) {
+		t.Fatalf("Find without results = %v, want ErrNoSearchResults", err)
+	}
+}
+
+func TestFindGameById(t *testing.T) {
+	f := newTestFinder(t)
+
+	game, err := f.FindGameById(context.Background(), 13537)
+	if err != nil {
+		t.Fatalf("FindGameById: %v", err)
+	}
+
+	if game.Source != storage.Rawg || game.Name != "Half-Life 2" || game.URL != "https://rawg.io/games/half-life-2" {
+		t.Fatalf("FindGameById = %+v", game)
+	}
+	if game.Developer != "Valve Software" || len(game.Genres) != 2 || game.CoverURL == "" || game.Summary == "" {
+		t.Fatalf("FindGameById details = %+v", game)
+	}
+
+	want := []api.PlatformDate{
+		{Platform: api.Platform{Id: 4, Name: "PC"}, Date: time.Date(2004, 11, 16, 0, 0, 0, 0, time.UTC)},
+		{Platform: api.Platform{Id: 14, Name: "Xbox 360"}, Date: time.Date(2007, 10, 10, 0, 0, 0, 0, time.UTC)},
+		//Без даты для платформы берётся дата выхода игры
+		{Platform: api.Platform{Id: 1, Name: "Xbox One"}, Date: time.Date(2004, 11, 16, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(game.ReleaseDates) != len(want) {
+		t.Fatalf("FindGameById release dates = %+v, want %+v", game.ReleaseDates, want)
+	}
+	for i := range want {
+		if game.ReleaseDates[i] != want[i] {
+			t.Errorf("release date %d = %+v, want %+v", i, game.ReleaseDates[i], want[i])
+		}
+	}
+
+	if _, err := f.FindGameById(context.Background(), 1); !errors.Is(err, api.ErrNoSearchResults) {
+		t.Fatalf("FindGameById for unknown game = %v, want ErrNoSearchResults", err)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	f := newTestFinder(t)
+	f.key = "wrong"
+
+	_, err := f.Find(context.Background(), "Half-Life 2")
+	if err == nil || errors.Is(err, api.ErrNoSearchResults) {
+		t.Fatalf("Find with wrong key = %v, want api error", err)
+	}
+}
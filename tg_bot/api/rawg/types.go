@@ -0,0 +1,63 @@
+package rawg
+
+import (
+	"encoding/json"
+	"tg_game_wishlist/lib/e"
+	"time"
+)
+
+type SearchResponse struct {
+	Count   int    `json:"count"`
+	Results []Game `json:"results"`
+}
+
+type Game struct {
+	Id              int               `json:"id"`
+	Slug            string            `json:"slug"`
+	Name            string            `json:"name"`
+	Released        Date              `json:"released"`
+	DescriptionRaw  string            `json:"description_raw"`
+	BackgroundImage string            `json:"background_image"`
+	Genres          []Named           `json:"genres"`
+	Developers      []Named           `json:"developers"`
+	Platforms       []PlatformRelease `json:"platforms"`
+}
+
+type Named struct {
+	Name string `json:"name"`
+}
+
+// PlatformRelease платформа игры; released_at RAWG заполняет только в данных одной игры, в поиске его нет
+type PlatformRelease struct {
+	Platform   Platform `json:"platform"`
+	ReleasedAt Date     `json:"released_at"`
+}
+
+type Platform struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// Date дата в формате 2006-01-02; null и пустая строка означают, что дата неизвестна
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return e.Wrap("can't unmarshal json date", err)
+	}
+	if value == nil || *value == "" {
+		d.Time = time.Time{}
+		return nil
+	}
+
+	t, err := time.Parse(time.DateOnly, *value)
+	if err != nil {
+		return e.Wrap("can't parse json date", err)
+	}
+	d.Time = t
+
+	return nil
+}
@@ -10,6 +10,8 @@ import (
 type Finder interface {
 	Find(ctx context.Context, name string) ([]SearchResult, error)
 	FindGameById(ctx context.Context, gameId int) (*Game, error)
+	// Source источник, из которого берутся id игр; id разных источников пересекаются
+	Source() storage.Source
 }
 
 var (
@@ -19,6 +21,7 @@ var (
 type SearchResult struct {
 	Id               int
 	Name             string
+	URL              string
 	FirstReleaseDate time.Time
 }
 
@@ -28,6 +31,10 @@ type Game struct {
 	URL          string
 	ReleaseDates []PlatformDate
 	Source       storage.Source
+	CoverURL     string
+	Summary      string
+	Genres       []string
+	Developer    string
 }
 
 type PlatformDate struct {
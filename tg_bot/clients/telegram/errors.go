@@ -0,0 +1,58 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrRateLimited  = errors.New("too many requests")
+	ErrChatNotFound = errors.New("chat not found")
+	ErrBotBlocked   = errors.New("bot can't send messages to the chat")
+	ErrBadRequest   = errors.New("bad request")
+)
+
+// APIError ответ Telegram Bot API с ok = false, сравнивается через errors.Is с ErrRateLimited и другими
+type APIError struct {
+	Code        int
+	Description string
+	RetryAfter  time.Duration
+	kind        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram api error %d: %s", e.Code, e.Description)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+func newAPIError(resp Response) *APIError {
+	err := &APIError{
+		Code:        resp.ErrorCode,
+		Description: resp.Description,
+	}
+	if resp.Parameters != nil {
+		err.RetryAfter = time.Duration(resp.Parameters.RetryAfter) * time.Second
+	}
+
+	description := strings.ToLower(resp.Description)
+
+	switch {
+	case resp.ErrorCode == http.StatusTooManyRequests:
+		err.kind = ErrRateLimited
+	case resp.ErrorCode == http.StatusForbidden:
+		//bot was blocked by the user, user is deactivated, bot was kicked from the group chat
+		err.kind = ErrBotBlocked
+	case strings.Contains(description, "chat not found"):
+		err.kind = ErrChatNotFound
+	case resp.ErrorCode == http.StatusBadRequest:
+		err.kind = ErrBadRequest
+	}
+
+	return err
+}
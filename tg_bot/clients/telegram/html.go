@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ParseMode string
+
+const (
+	PlainText     ParseMode = ""
+	ParseModeHTML ParseMode = "HTML"
+)
+
+var htmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+)
+
+// EscapeHTML экранирует строку для сообщений с ParseModeHTML.
+// Всё, что пришло от пользователя или из IGDB, вставляется в такие сообщения только через неё.
+func EscapeHTML(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+// Bold выделяет текст жирным, текст экранируется
+func Bold(text string) string {
+	return "<b>" + EscapeHTML(text) + "</b>"
+}
+
+// Link делает текст ссылкой; без адреса возвращается просто экранированный текст
+func Link(text string, url string) string {
+	if url == "" {
+		return EscapeHTML(text)
+	}
+
+	return fmt.Sprintf(`<a href="%s">%s</a>`, EscapeHTML(url), EscapeHTML(text))
+}
@@ -0,0 +1,32 @@
+package telegram
+
+import "testing"
+
+func TestEscapeHTML(t *testing.T) {
+	got := EscapeHTML(`Tom & Jerry <"Remastered">`)
+	want := "Tom &amp; Jerry &lt;&quot;Remastered&quot;&gt;"
+	if got != want {
+		t.Fatalf("EscapeHTML = %q, want %q", got, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		url  string
+		want string
+	}{
+		{"plain", "Doom", "https://www.igdb.com/games/doom", `<a href="https://www.igdb.com/games/doom">Doom</a>`},
+		{"escaped", "<Doom> & co", `https://example.com/?a=1&b="2"`, `<a href="https://example.com/?a=1&amp;b=&quot;2&quot;">&lt;Doom&gt; &amp; co</a>`},
+		{"no url", "A < B", "", "A &lt; B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Link(tt.text, tt.url); got != tt.want {
+				t.Fatalf("Link = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Queue отправляет сообщения через Client с учётом лимитов Telegram:
+// общего на все чаты и отдельного на каждый чат. Сообщения одного чата уходят в порядке вызова.
+// Остальные методы Client вызываются напрямую.
+type Queue struct {
+	*Client
+	//sender отправляет сообщения из очереди без собственных повторов, чтобы каждая попытка проходила через лимиты очереди
+	sender       *Client
+	global       *rate.Limiter
+	chatInterval time.Duration
+
+	mu    sync.Mutex
+	chats map[int]*chatQueue
+}
+
+type chatQueue struct {
+	jobs     []*sendJob
+	lastSent time.Time
+}
+
+type sendJob struct {
+	ctx  context.Context
+	send func(ctx context.Context) error
+	done chan error
+}
+
+func NewQueue(client *Client, messagesPerSecond int, chatInterval time.Duration) *Queue {
+	var sender *Client
+	if client != nil {
+		sender = client.withoutRetries()
+	}
+
+	return &Queue{
+		Client:       client,
+		sender:       sender,
+		global:       rate.NewLimiter(rate.Limit(messagesPerSecond), messagesPerSecond),
+		chatInterval: chatInterval,
+		chats:        make(map[int]*chatQueue),
+	}
+}
+
+func (q *Queue) SendMessage(ctx context.Context, chatId int, text string, parseMode ParseMode) error {
+	return q.Do(ctx, chatId, func(ctx context.Context) error {
+		return q.sender.SendMessage(ctx, chatId, text, parseMode)
+	})
+}
+
+func (q *Queue) SendMessageWithKeyboard(ctx context.Context, chatId int, text string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
+	return q.Do(ctx, chatId, func(ctx context.Context) error {
+		return q.sender.SendMessageWithKeyboard(ctx, chatId, text, parseMode, keyboard)
+	})
+}
+
+func (q *Queue) SendPhoto(ctx context.Context, chatId int, photoURL string, caption string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
+	return q.Do(ctx, chatId, func(ctx context.Context) error {
+		return q.sender.SendPhoto(ctx, chatId, photoURL, caption, parseMode, keyboard)
+	})
+}
+
+func (q *Queue) EditMessageText(ctx context.Context, chatId int, messageId int, text string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
+	return q.Do(ctx, chatId, func(ctx context.Context) error {
+		return q.sender.EditMessageText(ctx, chatId, messageId, text, parseMode, keyboard)
+	})
+}
+
+func (q *Queue) EditMessageReplyMarkup(ctx context.Context, chatId int, messageId int, keyboard *InlineKeyboardMarkup) error {
+	return q.Do(ctx, chatId, func(ctx context.Context) error {
+		return q.sender.EditMessageReplyMarkup(ctx, chatId, messageId, keyboard)
+	})
+}
+
+// Do ставит отправку в очередь чата и ждёт её результата
+func (q *Queue) Do(ctx context.Context, chatId int, send func(ctx context.Context) error) error {
+	job := &sendJob{
+		ctx:  ctx,
+		send: send,
+		done: make(chan error, 1),
+	}
+
+	q.enqueue(chatId, job)
+
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) enqueue(chatId int, job *sendJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cq, ok := q.chats[chatId]
+	if !ok {
+		cq = &chatQueue{}
+		q.chats[chatId] = cq
+		go q.run(chatId, cq)
+	}
+
+	cq.jobs = append(cq.jobs, job)
+}
+
+// run обрабатывает очередь одного чата и завершается, когда она пуста и интервал чата истёк
+func (q *Queue) run(chatId int, cq *chatQueue) {
+	for {
+		q.mu.Lock()
+		if len(cq.jobs) == 0 {
+			wait := q.chatInterval - time.Since(cq.lastSent)
+			if wait <= 0 {
+				delete(q.chats, chatId)
+				q.mu.Unlock()
+				return
+			}
+			q.mu.Unlock()
+			time.Sleep(wait)
+			continue
+		}
+
+		job := cq.jobs[0]
+		cq.jobs = cq.jobs[1:]
+		q.mu.Unlock()
+
+		job.done <- q.send(cq, job)
+	}
+}
+
+func (q *Queue) send(cq *chatQueue, job *sendJob) error {
+	for attempt := 0; ; attempt++ {
+		if err := job.ctx.Err(); err != nil {
+			return err
+		}
+
+		if wait := q.chatInterval - time.Since(cq.lastSent); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		if err := q.global.Wait(job.ctx); err != nil {
+			return err
+		}
+
+		err := job.send(job.ctx)
+		cq.lastSent = time.Now()
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !errors.Is(apiErr, ErrRateLimited) || attempt >= maxRetries {
+			return err
+		}
+
+		//Следующая попытка для этого чата не раньше retry_after
+		retryAfter := max(apiErr.RetryAfter, q.chatInterval)
+		log.Printf("send queue: rate limited, retry after %s", retryAfter)
+		cq.lastSent = time.Now().Add(retryAfter - q.chatInterval)
+	}
+}
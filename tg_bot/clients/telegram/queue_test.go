@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueChatOrderAndInterval(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	q := NewQueue(nil, 1000, interval)
+
+	var mu sync.Mutex
+	sent := make(map[int][]time.Time)
+	order := make(map[int][]int)
+
+	var wg sync.WaitGroup
+	for chatId := 1; chatId <= 2; chatId++ {
+		wg.Add(1)
+		go func(chatId int) {
+			defer wg.Done()
+			for i := 0; i < 3; i++ {
+				err := q.Do(context.Background(), chatId, func(ctx context.Context) error {
+					mu.Lock()
+					defer mu.Unlock()
+					sent[chatId] = append(sent[chatId], time.Now())
+					order[chatId] = append(order[chatId], i)
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Do: %v", err)
+				}
+			}
+		}(chatId)
+	}
+	wg.Wait()
+
+	for chatId := 1; chatId <= 2; chatId++ {
+		if got := order[chatId]; len(got) != 3 || got[0] != 0 || got[1] != 1 || got[2] != 2 {
+			t.Fatalf("chat %d order = %v, want [0 1 2]", chatId, got)
+		}
+		for i := 1; i < len(sent[chatId]); i++ {
+			if gap := sent[chatId][i].Sub(sent[chatId][i-1]); gap < interval {
+				t.Fatalf("chat %d gap between messages = %s, want at least %s", chatId, gap, interval)
+			}
+		}
+	}
+
+	//Разные чаты не ждут друг друга
+	if gap := sent[2][0].Sub(sent[1][0]).Abs(); gap >= interval {
+		t.Fatalf("first messages of different chats are %s apart, want less than %s", gap, interval)
+	}
+}
+
+func TestQueueRetryOnRateLimit(t *testing.T) {
+	q := NewQueue(nil, 1000, time.Millisecond)
+
+	attempts := 0
+	err := q.Do(context.Background(), 1, func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return &APIError{Code: 429, RetryAfter: 10 * time.Millisecond, kind: ErrRateLimited}
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Do = %v after %d attempts, want success after 2", err, attempts)
+	}
+}
+
+func TestQueueRetriesOnlyOnce(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`{"ok": false, "error_code": 429, "description": "Too Many Requests", "parameters": {"retry_after": 0}}`))
+	}))
+	defer srv.Close()
+
+	client := New(strings.TrimPrefix(srv.URL, "https://"), "token", 0)
+	client.client = *srv.Client()
+	q := NewQueue(client, 1000, time.Millisecond)
+
+	err := q.SendMessage(context.Background(), 1, "text", PlainText)
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("SendMessage = %v, want ErrRateLimited", err)
+	}
+	//Повторяет только очередь: первая попытка и maxRetries повторов
+	if got := requests.Load(); got != maxRetries+1 {
+		t.Fatalf("server got %d requests, want %d", got, maxRetries+1)
+	}
+}
@@ -0,0 +1,186 @@
+package telegram
+
+import "strings"
+
+const (
+	// MaxMessageLength ограничение Telegram на длину текста сообщения в UTF-16 символах
+	MaxMessageLength = 4096
+	// MaxCaptionLength ограничение на длину подписи к картинке
+	MaxCaptionLength = 1024
+)
+
+const entrySeparator = "\n\n"
+
+// SplitMessage собирает из заголовка и записей сообщения не длиннее MaxMessageLength для ParseModeHTML.
+// Записи разделяются пустой строкой и не разрываются между сообщениями; заголовок идёт только в первом.
+// Запись, которая не помещается даже в отдельное сообщение, режется по символам вне тегов и сущностей (cutHTML).
+func SplitMessage(header string, entries []string) []string {
+	return splitMessage(header, entries, MaxMessageLength)
+}
+
+func splitMessage(header string, entries []string, limit int) []string {
+	var res []string
+	var builder strings.Builder
+	length := 0
+
+	flush := func() {
+		if builder.Len() > 0 {
+			res = append(res, builder.String())
+			builder.Reset()
+			length = 0
+		}
+	}
+
+	add := func(part string) {
+		partLength := TextLength(part)
+
+		if length > 0 && length+TextLength(entrySeparator)+partLength > limit {
+			flush()
+		}
+
+		if length > 0 {
+			builder.WriteString(entrySeparator)
+			length += TextLength(entrySeparator)
+		}
+
+		for partLength > limit {
+			head, tail := cutHTML(part, limit)
+			builder.WriteString(head)
+			flush()
+			part, partLength = tail, TextLength(tail)
+		}
+
+		builder.WriteString(part)
+		length += partLength
+	}
+
+	if header != "" {
+		add(header)
+	}
+	for _, entry := range entries {
+		add(entry)
+	}
+	flush()
+
+	return res
+}
+
+// Truncate обрезает текст до limit UTF-16 символов, обрезанный текст заканчивается многоточием
+func Truncate(text string, limit int) string {
+	if TextLength(text) <= limit {
+		return text
+	}
+
+	head, _ := cut(text, limit-1)
+
+	return strings.TrimRight(head, " \n") + "…"
+}
+
+// TextLength длина текста так, как её считает Telegram (в UTF-16)
+func TextLength(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16Len(r)
+	}
+
+	return length
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// cut отрезает от текста начало длиной не больше limit UTF-16 символов
+func cut(text string, limit int) (string, string) {
+	length := 0
+	for i, r := range text {
+		if length+utf16Len(r) > limit {
+			return text[:i], text[i:]
+		}
+		length += utf16Len(r)
+	}
+
+	return text, ""
+}
+
+// cutHTML как cut, но для текста с HTML-разметкой: не режет теги и сущности вроде &amp;,
+// а теги, открытые на месте разреза, закрывает в начале и открывает заново в остатке
+func cutHTML(text string, limit int) (string, string) {
+	var open []string
+	length := 0
+	pos := 0
+	//Хотя бы один символ текста попадает в начало, иначе остаток с заново открытыми тегами не станет короче
+	taken := false
+
+	for pos < len(text) {
+		token := htmlToken(text[pos:])
+		tokenLength := TextLength(token)
+
+		next := open
+		isTag := strings.HasPrefix(token, "<") && len(token) > 1
+		if isTag {
+			next = applyTag(open, token)
+		}
+
+		if taken && length+tokenLength+TextLength(closeTags(next)) > limit {
+			break
+		}
+
+		length += tokenLength
+		pos += len(token)
+		open = next
+		taken = taken || !isTag
+	}
+
+	if pos == len(text) {
+		return text, ""
+	}
+
+	return text[:pos] + closeTags(open), strings.Join(open, "") + text[pos:]
+}
+
+// htmlToken начало текста: тег целиком, сущность целиком или один символ
+func htmlToken(text string) string {
+	switch text[0] {
+	case '<':
+		if end := strings.IndexByte(text, '>'); end > 0 {
+			return text[:end+1]
+		}
+	case '&':
+		if end := strings.IndexByte(text, ';'); end > 0 && !strings.ContainsAny(text[1:end], " <&\n") {
+			return text[:end+1]
+		}
+	}
+
+	for _, r := range text {
+		return string(r)
+	}
+
+	return text
+}
+
+// applyTag возвращает открытые теги после tag; исходный срез не меняется
+func applyTag(open []string, tag string) []string {
+	if strings.HasPrefix(tag, "</") {
+		if len(open) == 0 {
+			return open
+		}
+		return open[:len(open)-1]
+	}
+
+	return append(open[:len(open):len(open)], tag)
+}
+
+// closeTags закрывающие теги для открытых, в обратном порядке
+func closeTags(open []string) string {
+	var builder strings.Builder
+	for i := len(open) - 1; i >= 0; i-- {
+		name, _, _ := strings.Cut(strings.Trim(open[i], "<>"), " ")
+		builder.WriteString("</" + name + ">")
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		entries []string
+		limit   int
+		want    []string
+	}{
+		{
+			name:    "fits in one message",
+			header:  "list",
+			entries: []string{"a", "b"},
+			limit:   100,
+			want:    []string{"list\n\na\n\nb"},
+		},
+		{
+			name:    "splits on entry boundary",
+			header:  "list",
+			entries: []string{"aaaa", "bbbb", "cccc"},
+			limit:   14,
+			want:    []string{"list\n\naaaa", "bbbb\n\ncccc"},
+		},
+		{
+			name:    "cuts entry longer than limit",
+			entries: []string{"abcdefgh"},
+			limit:   3,
+			want:    []string{"abc", "def", "gh"},
+		},
+		{
+			name:    "counts emoji as two characters",
+			entries: []string{"🎮🎮", "🎮"},
+			limit:   5,
+			want:    []string{"🎮🎮", "🎮"},
+		},
+		{
+			name:  "empty",
+			limit: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.header, tt.entries, tt.limit)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Fatalf("splitMessage = %q, want %q", got, tt.want)
+			}
+			for _, msg := range got {
+				if TextLength(msg) > tt.limit {
+					t.Fatalf("message %q is longer than %d", msg, tt.limit)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"Half-Life 2: Episode Two", 12, "Half-Life 2…"},
+		{"🎮🎮🎮🎮", 5, "🎮🎮…"},
+	}
+
+	for _, tt := range tests {
+		if got := Truncate(tt.text, tt.limit); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+		if got := Truncate(tt.text, tt.limit); TextLength(got) > tt.limit {
+			t.Errorf("Truncate(%q, %d) is longer than limit", tt.text, tt.limit)
+		}
+	}
+}
+
+func TestSplitMessageHTML(t *testing.T) {
+	entry := "🔥 " + Link(strings.Repeat("Tom & Jerry ", 5), "https://www.igdb.com/games/tom-and-jerry") + "\n🕹️ " + Bold("PC")
+
+	const limit = 80
+	got := splitMessage("", []string{entry}, limit)
+	if len(got) < 2 {
+		t.Fatalf("splitMessage = %q, want entry to be cut", got)
+	}
+
+	var text strings.Builder
+	for _, msg := range got {
+		if TextLength(msg) > limit {
+			t.Fatalf("message %q is longer than %d", msg, limit)
+		}
+		//Каждое сообщение — корректный HTML: теги закрыты, сущности целые
+		if strings.Count(msg, "<a ") != strings.Count(msg, "</a>") || strings.Count(msg, "<b>") != strings.Count(msg, "</b>") {
+			t.Fatalf("message %q has unclosed tags", msg)
+		}
+		if strings.Count(msg, "&") != strings.Count(msg, "&amp;") {
+			t.Fatalf("message %q has a broken entity", msg)
+		}
+		text.WriteString(msg)
+	}
+
+	//Без разметки сообщения вместе дают исходный текст
+	strip := strings.NewReplacer(`<a href="https://www.igdb.com/games/tom-and-jerry">`, "", "</a>", "", "<b>", "", "</b>", "")
+	if strip.Replace(text.String()) != strip.Replace(entry) {
+		t.Fatalf("messages %q don't add up to %q", got, entry)
+	}
+}
+
+func TestCutHTML(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		head  string
+		tail  string
+	}{
+		{"ab&amp;cd", 4, "ab", "&amp;cd"},
+		{"<b>abcdef</b>", 10, "<b>abc</b>", "<b>def</b>"},
+		{`<a href="u">abcdef</a>`, 18, `<a href="u">ab</a>`, `<a href="u">cdef</a>`},
+		{"short", 10, "short", ""},
+	}
+
+	for _, tt := range tests {
+		head, tail := cutHTML(tt.text, tt.limit)
+		if head != tt.head || tail != tt.tail {
+			t.Errorf("cutHTML(%q, %d) = %q, %q, want %q, %q", tt.text, tt.limit, head, tail, tt.head, tt.tail)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package telegram
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -17,12 +18,25 @@ type Client struct {
 	host     string
 	basePath string
 	client   http.Client
+	//Сколько раз повторять запрос после 429; у Client, через который отправляет Queue, 0 — повторяет сама очередь
+	retries int
 }
 
 const (
-	getUpdatesMethod     = "getUpdates"
-	sendMessageMethod    = "sendMessage"
-	answerCallbackMethod = "answerCallbackQuery"
+	getUpdatesMethod             = "getUpdates"
+	sendMessageMethod            = "sendMessage"
+	sendPhotoMethod              = "sendPhoto"
+	answerCallbackMethod         = "answerCallbackQuery"
+	editMessageTextMethod        = "editMessageText"
+	editMessageReplyMarkupMethod = "editMessageReplyMarkup"
+	deleteMessageMethod          = "deleteMessage"
+	setWebhookMethod             = "setWebhook"
+	deleteWebhookMethod          = "deleteWebhook"
+	setMyCommandsMethod          = "setMyCommands"
+	answerInlineQueryMethod      = "answerInlineQuery"
+	getMeMethod                  = "getMe"
+
+	maxRetries = 3
 )
 
 func New(host string, token string, timeout int) *Client {
@@ -32,9 +46,18 @@ func New(host string, token string, timeout int) *Client {
 		client: http.Client{
 			Timeout: 65 * time.Second,
 		},
+		retries: maxRetries,
 	}
 }
 
+// withoutRetries копия клиента, которая возвращает 429 сразу
+func (c *Client) withoutRetries() *Client {
+	res := *c
+	res.retries = 0
+
+	return &res
+}
+
 func newBasePath(token string) string {
 	return "bot" + token
 }
@@ -52,20 +75,39 @@ func (c *Client) Updates(ctx context.Context, offset int, limit int, timeout int
 		return nil, err
 	}
 
-	var res UpdatesResponse
+	var res []Update
 
 	if err := json.Unmarshal(data, &res); err != nil {
 		return nil, err
 	}
 
-	return res.Result, nil
+	return res, nil
+}
+
+// Me возвращает аккаунт самого бота, username нужен для ссылок t.me/<bot>
+func (c *Client) Me(ctx context.Context) (me *From, err error) {
+	defer func() { err = e.WrapIfNil("can't get me", err) }()
+
+	data, err := c.doRequest(ctx, getMeMethod, http.MethodGet, url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var res From
+
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
 }
 
-func (c *Client) SendMessage(ctx context.Context, chatId int, text string) error {
+func (c *Client) SendMessage(ctx context.Context, chatId int, text string, parseMode ParseMode) error {
 	q := url.Values{}
 	q.Add("chat_id", strconv.Itoa(chatId))
 	q.Add("text", text)
 	q.Add("link_preview_options", "{\"is_disabled\": true}")
+	addParseMode(q, parseMode)
 
 	_, err := c.doRequest(ctx, sendMessageMethod, http.MethodGet, q)
 	if err != nil {
@@ -75,7 +117,7 @@ func (c *Client) SendMessage(ctx context.Context, chatId int, text string) error
 	return nil
 }
 
-func (c *Client) SendMessageWithKeyboard(ctx context.Context, chatId int, text string, keyboard *InlineKeyboardMarkup) error {
+func (c *Client) SendMessageWithKeyboard(ctx context.Context, chatId int, text string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
 	jsonKeyboard, err := json.Marshal(keyboard)
 	if err != nil {
 		return e.Wrap("can't marshal inline keyboard in message", err)
@@ -85,6 +127,7 @@ func (c *Client) SendMessageWithKeyboard(ctx context.Context, chatId int, text s
 	q.Add("chat_id", strconv.Itoa(chatId))
 	q.Add("text", text)
 	q.Add("link_preview_options", "{\"is_disabled\": true}")
+	addParseMode(q, parseMode)
 	q.Add("reply_markup", string(jsonKeyboard))
 
 	_, err = c.doRequest(ctx, sendMessageMethod, http.MethodPost, q)
@@ -95,6 +138,90 @@ func (c *Client) SendMessageWithKeyboard(ctx context.Context, chatId int, text s
 	return nil
 }
 
+// SendPhoto отправляет картинку по ссылке; подпись не длиннее MaxCaptionLength, keyboard может быть nil
+func (c *Client) SendPhoto(ctx context.Context, chatId int, photoURL string, caption string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
+	q := url.Values{}
+	q.Add("chat_id", strconv.Itoa(chatId))
+	q.Add("photo", photoURL)
+	q.Add("caption", caption)
+	addParseMode(q, parseMode)
+
+	if keyboard != nil {
+		jsonKeyboard, err := json.Marshal(keyboard)
+		if err != nil {
+			return e.Wrap("can't marshal inline keyboard in photo", err)
+		}
+		q.Add("reply_markup", string(jsonKeyboard))
+	}
+
+	_, err := c.doRequest(ctx, sendPhotoMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't send photo", err)
+	}
+
+	return nil
+}
+
+// EditMessageText заменяет текст сообщения; при keyboard == nil клавиатура убирается
+func (c *Client) EditMessageText(ctx context.Context, chatId int, messageId int, text string, parseMode ParseMode, keyboard *InlineKeyboardMarkup) error {
+	q := url.Values{}
+	q.Add("chat_id", strconv.Itoa(chatId))
+	q.Add("message_id", strconv.Itoa(messageId))
+	q.Add("text", text)
+	q.Add("link_preview_options", "{\"is_disabled\": true}")
+	addParseMode(q, parseMode)
+
+	if keyboard != nil {
+		jsonKeyboard, err := json.Marshal(keyboard)
+		if err != nil {
+			return e.Wrap("can't marshal inline keyboard in edited message", err)
+		}
+		q.Add("reply_markup", string(jsonKeyboard))
+	}
+
+	_, err := c.doRequest(ctx, editMessageTextMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't edit message text", err)
+	}
+
+	return nil
+}
+
+// EditMessageReplyMarkup заменяет клавиатуру сообщения; при keyboard == nil клавиатура убирается
+func (c *Client) EditMessageReplyMarkup(ctx context.Context, chatId int, messageId int, keyboard *InlineKeyboardMarkup) error {
+	q := url.Values{}
+	q.Add("chat_id", strconv.Itoa(chatId))
+	q.Add("message_id", strconv.Itoa(messageId))
+
+	if keyboard != nil {
+		jsonKeyboard, err := json.Marshal(keyboard)
+		if err != nil {
+			return e.Wrap("can't marshal inline keyboard", err)
+		}
+		q.Add("reply_markup", string(jsonKeyboard))
+	}
+
+	_, err := c.doRequest(ctx, editMessageReplyMarkupMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't edit message reply markup", err)
+	}
+
+	return nil
+}
+
+func (c *Client) DeleteMessage(ctx context.Context, chatId int, messageId int) error {
+	q := url.Values{}
+	q.Add("chat_id", strconv.Itoa(chatId))
+	q.Add("message_id", strconv.Itoa(messageId))
+
+	_, err := c.doRequest(ctx, deleteMessageMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't delete message", err)
+	}
+
+	return nil
+}
+
 func (c *Client) AnswerCallBack(ctx context.Context, callbackId string, text string, showAlert bool) error {
 	q := url.Values{}
 	q.Add("callback_query_id", callbackId)
@@ -110,9 +237,114 @@ func (c *Client) AnswerCallBack(ctx context.Context, callbackId string, text str
 
 }
 
+// AnswerInlineQuery отвечает на inline-запрос; cacheTime — сколько секунд Telegram может отдавать этот ответ без запроса к боту
+func (c *Client) AnswerInlineQuery(ctx context.Context, inlineQueryId string, results []InlineQueryResultArticle, cacheTime int) error {
+	if results == nil {
+		results = []InlineQueryResultArticle{}
+	}
+
+	jsonResults, err := json.Marshal(results)
+	if err != nil {
+		return e.Wrap("can't marshal inline query results", err)
+	}
+
+	q := url.Values{}
+	q.Add("inline_query_id", inlineQueryId)
+	q.Add("results", string(jsonResults))
+	q.Add("cache_time", strconv.Itoa(cacheTime))
+
+	_, err = c.doRequest(ctx, answerInlineQueryMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't answer inline query", err)
+	}
+
+	return nil
+}
+
+// SetWebhook включает доставку обновлений на url, Telegram передаёт secretToken в заголовке X-Telegram-Bot-Api-Secret-Token
+func (c *Client) SetWebhook(ctx context.Context, webhookURL string, secretToken string) error {
+	q := url.Values{}
+	q.Add("url", webhookURL)
+	q.Add("secret_token", secretToken)
+
+	_, err := c.doRequest(ctx, setWebhookMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't set webhook", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook отключает webhook, без этого getUpdates не работает
+func (c *Client) DeleteWebhook(ctx context.Context, dropPendingUpdates bool) error {
+	q := url.Values{}
+	q.Add("drop_pending_updates", strconv.FormatBool(dropPendingUpdates))
+
+	_, err := c.doRequest(ctx, deleteWebhookMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't delete webhook", err)
+	}
+
+	return nil
+}
+
+// SetMyCommands задаёт меню команд бота; для пустого languageCode меню показывается пользователям, для языка которых отдельного меню нет
+func (c *Client) SetMyCommands(ctx context.Context, commands []BotCommand, scope BotCommandScope, languageCode string) error {
+	jsonCommands, err := json.Marshal(commands)
+	if err != nil {
+		return e.Wrap("can't marshal bot commands", err)
+	}
+
+	jsonScope, err := json.Marshal(scope)
+	if err != nil {
+		return e.Wrap("can't marshal bot command scope", err)
+	}
+
+	q := url.Values{}
+	q.Add("commands", string(jsonCommands))
+	q.Add("scope", string(jsonScope))
+	if languageCode != "" {
+		q.Add("language_code", languageCode)
+	}
+
+	_, err = c.doRequest(ctx, setMyCommandsMethod, http.MethodPost, q)
+	if err != nil {
+		return e.Wrap("can't set my commands", err)
+	}
+
+	return nil
+}
+
+func addParseMode(q url.Values, parseMode ParseMode) {
+	if parseMode != PlainText {
+		q.Add("parse_mode", string(parseMode))
+	}
+}
+
+// doRequest возвращает поле result ответа; при 429 запрос повторяется после retry_after не больше c.retries раз
 func (c *Client) doRequest(ctx context.Context, method string, httpMethod string, q url.Values) (data []byte, err error) {
-	defer func() { err = e.WrapIfNil("can't do get request", err) }()
+	defer func() { err = e.WrapIfNil("can't do request", err) }()
+
+	for attempt := 0; ; attempt++ {
+		data, err = c.doRequestOnce(ctx, method, httpMethod, q)
 
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !errors.Is(apiErr, ErrRateLimited) || attempt >= c.retries {
+			return data, err
+		}
+
+		retryAfter := max(apiErr.RetryAfter, time.Second)
+		log.Printf("telegram rate limit on %s, retry after %s", method, retryAfter)
+
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) doRequestOnce(ctx context.Context, method string, httpMethod string, q url.Values) ([]byte, error) {
 	u := url.URL{
 		Scheme: "https",
 		Host:   c.host,
@@ -138,5 +370,15 @@ func (c *Client) doRequest(ctx context.Context, method string, httpMethod string
 		return nil, err
 	}
 
-	return body, nil
+	var res Response
+
+	if err := json.Unmarshal(body, &res); err != nil {
+		return nil, e.Wrap("can't unmarshal response", err)
+	}
+
+	if !res.Ok {
+		return nil, newAPIError(res)
+	}
+
+	return res.Result, nil
 }
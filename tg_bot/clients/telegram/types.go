@@ -1,39 +1,120 @@
 package telegram
 
-type UpdatesResponse struct {
-	Ok     bool     `json:"ok"`
-	Result []Update `json:"result"`
+import "encoding/json"
+
+type Response struct {
+	Ok          bool                `json:"ok"`
+	Result      json.RawMessage     `json:"result"`
+	ErrorCode   int                 `json:"error_code"`
+	Description string              `json:"description"`
+	Parameters  *ResponseParameters `json:"parameters"`
+}
+
+type ResponseParameters struct {
+	RetryAfter      int `json:"retry_after"`
+	MigrateToChatId int `json:"migrate_to_chat_id"`
 }
 
 type Update struct {
 	Id            int              `json:"update_id"`
 	Message       *IncomingMessage `json:"message"`
 	CallbackQuery *CallbackQuery   `json:"callback_query"`
+	InlineQuery   *InlineQuery     `json:"inline_query"`
+	// MyChatMember изменение статуса бота в чате: добавили в группу, удалили, заблокировали
+	MyChatMember *ChatMemberUpdated `json:"my_chat_member"`
 }
 
 type IncomingMessage struct {
-	Text string `json:"text"`
-	From From   `json:"from"`
-	Chat Chat   `json:"chat"`
+	MessageId int    `json:"message_id"`
+	Text      string `json:"text"`
+	From      From   `json:"from"`
+	Chat      Chat   `json:"chat"`
+	// MigrateToChatId приходит служебным сообщением в группе, которая стала супергруппой
+	MigrateToChatId int `json:"migrate_to_chat_id"`
+	// ReplyToMessage сообщение, на которое ответили
+	ReplyToMessage *IncomingMessage `json:"reply_to_message"`
 }
 
 type Chat struct {
-	Id int `json:"id"`
+	Id    int    `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
 }
 
 type From struct {
-	Username string `json:"username"`
+	Id        int    `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          From       `json:"from"`
+	NewChatMember ChatMember `json:"new_chat_member"`
 }
 
+type ChatMember struct {
+	Status string `json:"status"`
+	User   From   `json:"user"`
+}
+
+const (
+	MemberStatusCreator       = "creator"
+	MemberStatusAdministrator = "administrator"
+	MemberStatusMember        = "member"
+	MemberStatusRestricted    = "restricted"
+	MemberStatusLeft          = "left"
+	MemberStatusKicked        = "kicked"
+)
+
+type BotCommand struct {
+	Command     string `json:"command"`
+	Description string `json:"description"`
+}
+
+type BotCommandScope struct {
+	Type string `json:"type"`
+}
+
+var ScopeDefault = BotCommandScope{Type: "default"}
+
 //Inline
 
 type CallbackQuery struct {
-	Id      string           `json:"id"`
-	From    From             `json:"from"`
-	Message *IncomingMessage `json:"message"`
-	Data    string           `json:"data"`
+	Id   string `json:"id"`
+	From From   `json:"from"`
+	// Message не заполняется, если кнопка нажата в сообщении, отправленном через inline-режим
+	Message         *IncomingMessage `json:"message"`
+	InlineMessageId string           `json:"inline_message_id"`
+	Data            string           `json:"data"`
+}
+
+//Inline mode
+
+type InlineQuery struct {
+	Id    string `json:"id"`
+	From  From   `json:"from"`
+	Query string `json:"query"`
 }
 
+type InlineQueryResultArticle struct {
+	Type                string                `json:"type"`
+	Id                  string                `json:"id"`
+	Title               string                `json:"title"`
+	Description         string                `json:"description,omitempty"`
+	URL                 string                `json:"url,omitempty"`
+	InputMessageContent InputMessageContent   `json:"input_message_content"`
+	ReplyMarkup         *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type InputMessageContent struct {
+	MessageText string    `json:"message_text"`
+	ParseMode   ParseMode `json:"parse_mode,omitempty"`
+}
+
+const ArticleResult = "article"
+
 type InlineKeyboardMarkup struct {
 	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
 }
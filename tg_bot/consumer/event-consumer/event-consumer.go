@@ -32,13 +32,16 @@ func (c Consumer) Start() error {
 			continue
 		}
 
-		if len(gotEvents) == 0 {
-			continue
+		if len(gotEvents) > 0 {
+			if err := c.handleEvents(context.Background(), gotEvents); err != nil {
+				log.Print(err)
+				continue
+			}
 		}
 
-		if err := c.handleEvents(context.Background(), gotEvents); err != nil {
-			log.Print(err)
-			continue
+		//Прогресс сохраняется только после обработки всей пачки
+		if err := c.fetcher.Commit(context.Background()); err != nil {
+			log.Printf("[ERR] consumer: %s", err.Error())
 		}
 	}
 }
@@ -49,7 +52,11 @@ func (c Consumer) handleEvents(ctx context.Context, events []events.Event) error
 
 		if err := c.processor.Process(ctx, event); err != nil {
 			log.Printf("can't handle event: %s", err.Error())
-			continue
+		}
+
+		//Событие отмечается обработанным и после ошибки, чтобы не повторять его при повторной доставке
+		if err := c.fetcher.Ack(ctx, event); err != nil {
+			log.Printf("can't ack event: %s", err.Error())
 		}
 	}
 
@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -18,66 +19,167 @@ const (
 	AddCallback    = "add"
 	RemoveCallback = "remove"
 	AddWithoutDate = "add_without_date"
+
+	ListPageCallback   = "list_page"
+	RemovePageCallback = "remove_page"
+
+	DetailsCallback = "details"
+	CardAddCallback = "card_add"
+	BackCallback    = "back"
+
+	SharedPageCallback = "shared_page"
+	SharedAddCallback  = "shared_add"
 )
 
-func (p *Processor) doCallback(ctx context.Context, callbackId string, text string, chatID int, userName string) (err error) {
+// ErrBadCallbackData данные кнопки не того формата; клиент может прислать любые callback_data
+var ErrBadCallbackData = errors.New("bad callback data")
+
+// ErrNotOwner запись из чужого списка
+var ErrNotOwner = errors.New("wishlist belongs to another chat")
+
+// ErrPlatformNotFound в данных кнопки платформы, которых нет у игры
+var ErrPlatformNotFound = errors.New("platform not found")
+
+// callbackParts делит данные кнопки на части и проверяет, что их не меньше min
+func callbackParts(text string, min int) ([]string, error) {
+	parts := strings.Split(text, ":")
+	if len(parts) < min {
+		return nil, ErrBadCallbackData
+	}
+
+	return parts, nil
+}
+
+// messageId сообщение с нажатой кнопкой, ответ на нажатие редактирует его вместо отправки нового
+func (p *Processor) doCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int, from *storage.User) (err error) {
 	defer func() { err = e.WrapIfNil("can't process callback", err) }()
 
 	parts := strings.Split(text, ":")
 
 	switch parts[0] {
 	case SelectCallback:
-		return p.selectGameCallback(ctx, callbackId, text, chatID, userName)
+		return p.selectGameCallback(ctx, callbackId, text, chatID, messageId, from)
 	case AddCallback:
-		return p.addGameCallback(ctx, callbackId, text, chatID, userName)
+		return p.addGameCallback(ctx, callbackId, text, chatID, messageId, from)
 	case RemoveCallback:
-		return p.removeWishlistCallback(ctx, callbackId, text, chatID)
+		return p.removeWishlistCallback(ctx, callbackId, text, chatID, messageId, from)
 	case AddWithoutDate:
-		return p.addWithoutDateCallback(ctx, callbackId, chatID, userName)
+		return p.addWithoutDateCallback(ctx, callbackId, chatID, messageId, from)
+	case ListPageCallback:
+		return p.pageCallback(ctx, callbackId, text, chatID, messageId, from, p.editGameList)
+	case RemovePageCallback:
+		return p.pageCallback(ctx, callbackId, text, chatID, messageId, from, p.editRemoveList)
+	case DetailsCallback:
+		return p.detailsCallback(ctx, callbackId, text, chatID)
+	case CardAddCallback:
+		return p.cardAddCallback(ctx, callbackId, text, chatID, messageId, from)
+	case BackCallback:
+		return p.backCallback(ctx, callbackId, chatID, messageId)
+	case SharedPageCallback:
+		return p.sharedPageCallback(ctx, callbackId, text, chatID, messageId)
+	case SharedAddCallback:
+		return p.sharedAddCallback(ctx, callbackId, text, chatID, from)
 	}
 
 	return nil
 }
 
-func (p *Processor) addWithoutDateCallback(ctx context.Context, callbackId string, chatId int, userName string) (err error) {
+func (p *Processor) addWithoutDateCallback(ctx context.Context, callbackId string, chatId int, messageId int, from *storage.User) (err error) {
 	defer func() {
 		err = e.WrapIfNil("can't add game without date callback", err)
 		p.tg.AnswerCallBack(ctx, callbackId, "", false)
 	}()
 
-	state, ok := p.states[userName]
-	if !ok {
+	state, err := p.state(ctx, from.TelegramId)
+	if err != nil || state == nil {
 		return err
 	}
 
-	return p.addManualGame(ctx, chatId, userName, state.GameName, time.Time{})
+	return p.addManualGame(ctx, chatId, messageId, from, state.GameName, time.Time{})
 }
 
-func (p *Processor) removeWishlistCallback(ctx context.Context, callbackId string, text string, chatID int) (err error) {
+func (p *Processor) removeWishlistCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int, from *storage.User) (err error) {
 	defer func() {
 		err = e.WrapIfNil("can't process remove wishlist callback", err)
-		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+
+		answer := ""
+		if err == nil {
+			answer = msgRemoved
+		}
+		p.tg.AnswerCallBack(ctx, callbackId, answer, false)
 	}()
-	parts := strings.Split(text, ":")
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
 
 	wishlistId, err := strconv.Atoi(parts[1])
 	if err != nil {
 		return err
 	}
 
-	if err = p.storage.Remove(ctx, wishlistId); err != nil {
+	//У кнопок, отправленных до появления страниц, номера страницы нет
+	page := 0
+	if len(parts) > 2 {
+		if page, err = strconv.Atoi(parts[2]); err != nil {
+			return err
+		}
+	}
+
+	//Данные кнопки можно подделать: удалять можно только из списка этого чата
+	w, err := p.storage.Get(ctx, wishlistId)
+	if err != nil && !errors.Is(err, storage.ErrNoWishlist) {
 		return err
 	}
+	if err == nil {
+		if w.User.TelegramId != owner(chatID, from).TelegramId {
+			return ErrNotOwner
+		}
+		if err = p.storage.Remove(ctx, wishlistId); err != nil {
+			return err
+		}
+	}
 
-	return p.tg.SendMessage(ctx, chatID, msgRemoved)
+	//Список перерисовывается уже без удалённой игры
+	return p.editRemoveList(ctx, chatID, messageId, from, page)
 }
 
-func (p *Processor) addGameCallback(ctx context.Context, callbackId string, text string, chatID int, userName string) (err error) {
+// pageCallback перерисовывает сообщение со списком на странице из данных кнопки
+func (p *Processor) pageCallback(
+	ctx context.Context,
+	callbackId string,
+	text string,
+	chatID int,
+	messageId int,
+	from *storage.User,
+	edit func(ctx context.Context, chatId int, messageId int, from *storage.User, page int) error,
+) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process page callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
+
+	page, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	return edit(ctx, chatID, messageId, from, page)
+}
+
+func (p *Processor) addGameCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int, from *storage.User) (err error) {
 	defer func() {
 		err = e.WrapIfNil("can't process add game callback", err)
 		p.tg.AnswerCallBack(ctx, callbackId, "", false)
 	}()
-	parts := strings.Split(text, ":")
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
 
 	gameId, err := strconv.Atoi(parts[1])
 	if err != nil {
@@ -91,32 +193,51 @@ func (p *Processor) addGameCallback(ctx context.Context, callbackId string, text
 
 	//Если не указана платформа
 	if len(parts) < 3 {
-		return p.addApiGame(ctx, searchGame, nil, chatID, userName)
+		return p.addApiGame(ctx, searchGame, nil, chatID, messageId, from)
 	}
 
 	platformIds := strings.Split(parts[2], ",")
+	var platformDates []api.PlatformDate
 	for _, rd := range searchGame.ReleaseDates {
 		if slices.Contains(platformIds, strconv.Itoa(rd.Platform.Id)) {
-			return p.addApiGame(ctx, searchGame, &rd, chatID, userName)
+			platformDates = append(platformDates, rd)
 		}
 	}
 
-	return e.Wrap("platform not found", err)
+	if len(platformDates) == 0 {
+		return ErrPlatformNotFound
+	}
+
+	return p.addApiGame(ctx, searchGame, platformDates, chatID, messageId, from)
 }
 
-func (p *Processor) selectGameCallback(ctx context.Context, callbackId string, text string, chatID int, userName string) (err error) {
+func (p *Processor) selectGameCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int, from *storage.User) (err error) {
 	defer func() {
+		//Кнопка из inline-режима отвечает в личный чат, а пользователь мог ещё не запускать бота
+		if messageId == 0 && (errors.Is(err, telegram.ErrBotBlocked) || errors.Is(err, telegram.ErrChatNotFound)) {
+			p.tg.AnswerCallBack(ctx, callbackId, p.startBotFirst(ctx), true)
+			err = nil
+			return
+		}
 		err = e.WrapIfNil("can't process select game callback", err)
 		p.tg.AnswerCallBack(ctx, callbackId, "", false)
 	}()
 
-	parts := strings.Split(text, ":")
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
 
 	gameId, err := strconv.Atoi(parts[1])
 	if err != nil {
 		return err
 	}
 
+	return p.selectGame(ctx, gameId, chatID, messageId, from)
+}
+
+// selectGame добавляет игру или, если у платформ разные даты выхода, предлагает выбрать платформу
+func (p *Processor) selectGame(ctx context.Context, gameId int, chatID int, messageId int, from *storage.User) error {
 	searchGame, err := p.gameById(ctx, gameId)
 	if err != nil {
 		return err
@@ -127,15 +248,15 @@ func (p *Processor) selectGameCallback(ctx context.Context, callbackId string, t
 	//Случаи когда действия от пользователя не требуются
 	if p.isPastDates(searchGame.ReleaseDates) {
 		//Случай с всеми прошедшими датами (просто добавление без даты)
-		return p.addApiGame(ctx, searchGame, nil, chatID, userName)
+		return p.addApiGame(ctx, searchGame, nil, chatID, messageId, from)
 	} else if p.isSameDatePlatform(searchGame.ReleaseDates) {
 		//Случай с одинаковыми датами у всех платформ
 		if len(searchGame.ReleaseDates) > 0 && searchGame.ReleaseDates[0].Date.After(now) {
-			//Если дата в будущем, то добавляем с датой
-			return p.addApiGame(ctx, searchGame, &searchGame.ReleaseDates[0], chatID, userName)
+			//Если дата в будущем, то добавляем с датой для всех платформ
+			return p.addApiGame(ctx, searchGame, searchGame.ReleaseDates, chatID, messageId, from)
 		} else {
 			//Если даты нет или она в прошлом, то добавление без даты
-			return p.addApiGame(ctx, searchGame, nil, chatID, userName)
+			return p.addApiGame(ctx, searchGame, nil, chatID, messageId, from)
 		}
 	}
 
@@ -176,7 +297,9 @@ func (p *Processor) selectGameCallback(ctx context.Context, callbackId string, t
 		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
 	}
 
-	if err = p.tg.SendMessageWithKeyboard(ctx, chatID, msgPlatformDateChoice, &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}); err != nil {
+	//Клавиатура поиска заменяется выбором платформы
+	text := fmt.Sprintf("🎮 %s\n\n%s", searchGame.Name, msgPlatformDateChoice)
+	if err = p.replyWithKeyboard(ctx, chatID, messageId, text, &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}); err != nil {
 		return err
 	}
 
@@ -198,28 +321,35 @@ func (p *Processor) groupGamePlatformsByDate(releaseDates []api.PlatformDate) ma
 	return res
 }
 
-func (p *Processor) addApiGame(ctx context.Context, searchGame *api.Game, platformDate *api.PlatformDate, chatID int, userName string) (err error) {
+func (p *Processor) addApiGame(ctx context.Context, searchGame *api.Game, platformDates []api.PlatformDate, chatID int, messageId int, from *storage.User) (err error) {
 	defer func() {
 		err = e.WrapIfNil("can't add api game to storage", err)
 	}()
 
-	user := &storage.User{
-		Name:   userName,
-		ChatId: chatID,
-	}
-
 	game := &storage.Game{
 		Name:        searchGame.Name,
 		Source:      searchGame.Source,
 		ExternalURL: searchGame.URL,
+		ExternalId:  searchGame.Id,
+		ReleaseDate: p.earliestReleaseDate(searchGame.ReleaseDates),
 	}
 
 	wishlist := &storage.Wishlist{
-		User: user,
-		Game: game,
+		User:    owner(chatID, from),
+		AddedBy: from,
+		Game:    game,
 	}
-	if platformDate != nil {
-		wishlist.NotificationDate = platformDate.Date
+	//Уведомление приходит в день самого раннего релиза среди выбранных платформ
+	if len(platformDates) > 0 {
+		wishlist.NotificationDate = p.earliestReleaseDate(platformDates)
+		game.ReleaseDate = wishlist.NotificationDate
+	}
+	for _, pd := range platformDates {
+		wishlist.Platforms = append(wishlist.Platforms, storage.Platform{
+			Id:          pd.Platform.Id,
+			Name:        pd.Platform.Name,
+			ReleaseDate: pd.Date,
+		})
 	}
 
 	isExists, err := p.storage.IsExists(ctx, wishlist)
@@ -227,14 +357,14 @@ func (p *Processor) addApiGame(ctx context.Context, searchGame *api.Game, platfo
 		return err
 	}
 	if isExists {
-		return p.tg.SendMessage(ctx, chatID, msgAlreadyExists)
+		return p.reply(ctx, chatID, messageId, gameResult(inChat(chatID, msgAlreadyExists), game.Name))
 	}
 
 	if err := p.storage.Add(ctx, wishlist); err != nil {
 		return err
 	}
 
-	return p.tg.SendMessage(ctx, chatID, msgSaved)
+	return p.reply(ctx, chatID, messageId, gameResult(msgSaved, game.Name))
 }
 
 func (p *Processor) isSameDatePlatform(platformDates []api.PlatformDate) bool {
@@ -264,6 +394,21 @@ func (p *Processor) isPastDates(platformDates []api.PlatformDate) bool {
 	return true
 }
 
+func (p *Processor) earliestReleaseDate(platformDates []api.PlatformDate) time.Time {
+	var res time.Time
+
+	for _, date := range platformDates {
+		if date.Date.IsZero() {
+			continue
+		}
+		if res.IsZero() || date.Date.Before(res) {
+			res = date.Date
+		}
+	}
+
+	return res
+}
+
 func (p *Processor) gameById(ctx context.Context, gameId int) (game *api.Game, err error) {
 	defer func() { err = e.WrapIfNil("can't get game by id", err) }()
 
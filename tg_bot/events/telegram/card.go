@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+)
+
+// cardFromList отмечает карточку, открытую из /list: игра уже в списке, кнопка добавления не нужна
+const cardFromList = "list"
+
+// Описание короче этого не показывается, обрывок в пару слов ничего не объясняет
+const minSummaryLength = 40
+
+// detailsCallback отправляет карточку игры отдельным сообщением, чтобы список, из которого её открыли, остался на месте
+func (p *Processor) detailsCallback(ctx context.Context, callbackId string, text string, chatID int) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process details callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
+
+	gameId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+	fromList := len(parts) > 2 && parts[2] == cardFromList
+
+	game, err := p.gameById(ctx, gameId)
+	if err != nil {
+		return err
+	}
+
+	var buttons [][]telegram.InlineKeyboardButton
+	if !fromList {
+		buttons = append(buttons, []telegram.InlineKeyboardButton{{
+			Text:         btnAdd,
+			CallbackData: fmt.Sprintf("%s:%d", CardAddCallback, game.Id),
+		}})
+	}
+	buttons = append(buttons, []telegram.InlineKeyboardButton{{
+		Text:         btnBack,
+		CallbackData: BackCallback,
+	}})
+	keyboard := &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}
+
+	caption := p.gameCard(game)
+	if game.CoverURL == "" {
+		return p.tg.SendMessageWithKeyboard(ctx, chatID, caption, telegram.ParseModeHTML, keyboard)
+	}
+
+	return p.tg.SendPhoto(ctx, chatID, game.CoverURL, caption, telegram.ParseModeHTML, keyboard)
+}
+
+// cardAddCallback убирает кнопки с карточки и добавляет игру так же, как выбор из поиска
+func (p *Processor) cardAddCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int, from *storage.User) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process card add callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
+
+	gameId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	if err := p.tg.EditMessageReplyMarkup(ctx, chatID, messageId, nil); err != nil {
+		return err
+	}
+
+	//Карточка может быть картинкой, её текст не заменить, поэтому ответ идёт новым сообщением
+	return p.selectGame(ctx, gameId, chatID, 0, from)
+}
+
+// backCallback закрывает карточку, под ней остаётся список, из которого её открыли
+func (p *Processor) backCallback(ctx context.Context, callbackId string, chatID int, messageId int) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process back callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+
+	return p.tg.DeleteMessage(ctx, chatID, messageId)
+}
+
+// gameCard текст карточки игры для ParseModeHTML, помещается в подпись к картинке
+func (p *Processor) gameCard(game *api.Game) string {
+	var builder strings.Builder
+
+	builder.WriteString("🎮 <b>" + telegram.Link(game.Name, game.URL) + "</b>")
+	if game.Developer != "" {
+		builder.WriteString("\n" + msgDeveloper + telegram.EscapeHTML(game.Developer))
+	}
+	if len(game.Genres) > 0 {
+		builder.WriteString("\n" + msgGenres + telegram.EscapeHTML(strings.Join(game.Genres, ", ")))
+	}
+	if releaseDate := p.earliestReleaseDate(game.ReleaseDates); !releaseDate.IsZero() {
+		builder.WriteString("\n" + msgReleaseDate + telegram.Bold(releaseDate.Format("02.01.2006")))
+	}
+
+	//Длина с тегами больше той, что считает Telegram, так что описание точно поместится
+	rest := telegram.MaxCaptionLength - telegram.TextLength(builder.String()) - 2
+	if game.Summary != "" && rest >= minSummaryLength {
+		builder.WriteString("\n\n" + telegram.EscapeHTML(telegram.Truncate(game.Summary, rest)))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,53 @@
+package telegram
+
+import (
+	"strings"
+	"testing"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/api/rawg"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/storage"
+)
+
+func TestGameCard(t *testing.T) {
+	game := &api.Game{
+		Name:      "Tom & Jerry <3>",
+		URL:       "https://www.igdb.com/games/tom-and-jerry",
+		Developer: "Hanna & Barbera",
+		Genres:    []string{"Platform", "Arcade"},
+		Summary:   strings.Repeat("Кот гоняется за мышью. ", 100),
+	}
+
+	card := (&Processor{}).gameCard(game)
+
+	if length := telegram.TextLength(card); length > telegram.MaxCaptionLength {
+		t.Fatalf("card length = %d, want at most %d", length, telegram.MaxCaptionLength)
+	}
+	if !strings.Contains(card, `<a href="https://www.igdb.com/games/tom-and-jerry">Tom &amp; Jerry &lt;3&gt;</a>`) {
+		t.Fatalf("card has no escaped game link: %s", card)
+	}
+	if !strings.Contains(card, "Hanna &amp; Barbera") || !strings.Contains(card, "Platform, Arcade") {
+		t.Fatalf("card has no developer or genres: %s", card)
+	}
+	if !strings.HasSuffix(card, "…") {
+		t.Fatalf("long summary is not truncated: %s", card)
+	}
+}
+
+// Кнопки карточки и добавления из чужого списка передают ExternalId текущему источнику, а id IGDB и RAWG пересекаются
+func TestFromFinder(t *testing.T) {
+	p := &Processor{finder: rawg.New("", "")}
+
+	for _, tc := range []struct {
+		game storage.Game
+		want bool
+	}{
+		{storage.Game{Source: storage.Rawg, ExternalId: 13537}, true},
+		{storage.Game{Source: storage.Igdb, ExternalId: 233}, false},
+		{storage.Game{Source: storage.Manual}, false},
+	} {
+		if got := p.fromFinder(&tc.game); got != tc.want {
+			t.Errorf("fromFinder(%+v) = %v, want %v", tc.game, got, tc.want)
+		}
+	}
+}
@@ -15,86 +15,271 @@ import (
 )
 
 const (
-	HelpCmd   = "/help"
-	StartCmd  = "/start"
-	ListCmd   = "/list"
-	RemoveCmd = "/remove"
+	HelpCmd     = "/help"
+	StartCmd    = "/start"
+	ListCmd     = "/list"
+	ReleasedCmd = "/released"
+	UpcomingCmd = "/upcoming"
+	RemoveCmd   = "/remove"
+	AddCmd      = "/add"
+	ShareCmd    = "/share"
+	UnshareCmd  = "/unshare"
 )
 
-func (p *Processor) doCmd(ctx context.Context, text string, chatID int, userName string) error {
+// menuLanguages языки, для которых отправляется меню команд; "" — меню по умолчанию
+var menuLanguages = []string{"", "en"}
+
+type command struct {
+	name string
+	//Описание для меню по языкам из menuLanguages
+	descriptions map[string]string
+	//args — текст после команды, например payload ссылки /start <payload>
+	handle func(p *Processor, ctx context.Context, chatId int, from *storage.User, args string) error
+}
+
+// commands единый список команд: по нему работает doCmd и строится меню бота
+var commands = []command{
+	{
+		name:         StartCmd,
+		descriptions: map[string]string{"": "Начать работу с ботом", "en": "Start the bot"},
+		handle: func(p *Processor, ctx context.Context, chatId int, _ *storage.User, args string) error {
+			//Ссылка на чужой список приходит как /start w_<token>
+			if token, ok := strings.CutPrefix(args, sharePrefix); ok {
+				return p.sendSharedList(ctx, chatId, token)
+			}
+			return p.sendHello(ctx, chatId)
+		},
+	},
+	{
+		name:         HelpCmd,
+		descriptions: map[string]string{"": "Что умеет бот", "en": "What the bot can do"},
+		handle: func(p *Processor, ctx context.Context, chatId int, _ *storage.User, _ string) error {
+			return p.sendHelp(ctx, chatId)
+		},
+	},
+	{
+		name:         AddCmd,
+		descriptions: map[string]string{"": "Найти игру и добавить в список", "en": "Find a game and add it to the wishlist"},
+		handle: func(p *Processor, ctx context.Context, chatId int, from *storage.User, args string) error {
+			if args == "" {
+				return p.tg.SendMessage(ctx, chatId, msgAddUsage, telegram.PlainText)
+			}
+			return p.searchGameList(ctx, args, chatId, from)
+		},
+	},
+	{
+		name:         ListCmd,
+		descriptions: map[string]string{"": "Список желаемого", "en": "Show your wishlist"},
+		handle:       withoutArgs((*Processor).sendGameList),
+	},
+	{
+		name:         ReleasedCmd,
+		descriptions: map[string]string{"": "Уже вышедшие игры", "en": "Released games"},
+		handle:       withoutArgs((*Processor).sendReleasedList),
+	},
+	{
+		name:         UpcomingCmd,
+		descriptions: map[string]string{"": "Ожидаемые игры", "en": "Upcoming games"},
+		handle:       withoutArgs((*Processor).sendUpcomingList),
+	},
+	{
+		name:         RemoveCmd,
+		descriptions: map[string]string{"": "Удалить игру из списка", "en": "Remove a game from your wishlist"},
+		handle:       withoutArgs((*Processor).sendRemoveList),
+	},
+	{
+		name:         ShareCmd,
+		descriptions: map[string]string{"": "Поделиться списком по ссылке", "en": "Share your wishlist via link"},
+		handle:       withoutArgs((*Processor).sendShareLink),
+	},
+	{
+		name:         UnshareCmd,
+		descriptions: map[string]string{"": "Отозвать ссылки на список", "en": "Revoke wishlist links"},
+		handle:       withoutArgs((*Processor).unshare),
+	},
+}
+
+// withoutArgs адаптер для команд, которым текст после команды не нужен
+func withoutArgs(handle func(p *Processor, ctx context.Context, chatId int, from *storage.User) error) func(*Processor, context.Context, int, *storage.User, string) error {
+	return func(p *Processor, ctx context.Context, chatId int, from *storage.User, _ string) error {
+		return handle(p, ctx, chatId, from)
+	}
+}
+
+// parseCommand делит сообщение на команду, имя бота и аргументы. Имя бота Telegram добавляет к командам в группах:
+// /list@wishlist_bot; если его нет, bot пустой. Сообщение не с "/" — не команда, оно целиком уходит в args:
+// в названии игры тоже может быть @
+func parseCommand(text string) (name string, bot string, args string) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", text
+	}
+
+	name, args, _ = strings.Cut(text, " ")
+	name, bot, _ = strings.Cut(name, "@")
+
+	return name, bot, strings.TrimSpace(args)
+}
+
+// addressedToOther команда из группы, адресованная другому боту
+func (p *Processor) addressedToOther(ctx context.Context, bot string) (bool, error) {
+	if bot == "" {
+		return false, nil
+	}
+
+	me, err := p.isMe(ctx, bot)
+
+	return !me, err
+}
+
+// isMe username принадлежит этому боту; пустой username — не бот
+func (p *Processor) isMe(ctx context.Context, username string) (bool, error) {
+	if username == "" {
+		return false, nil
+	}
+
+	botName, err := p.botName(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.EqualFold(username, botName), nil
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+// RegisterCommands отправляет в Telegram меню команд из commands для каждого языка из menuLanguages
+func (p *Processor) RegisterCommands(ctx context.Context) error {
+	for _, lang := range menuLanguages {
+		menu := make([]telegram.BotCommand, 0, len(commands))
+		for _, cmd := range commands {
+			menu = append(menu, telegram.BotCommand{
+				Command:     strings.TrimPrefix(cmd.name, "/"),
+				Description: cmd.descriptions[lang],
+			})
+		}
+
+		if err := p.tg.SetMyCommands(ctx, menu, telegram.ScopeDefault, lang); err != nil {
+			return e.Wrap("can't register commands", err)
+		}
+	}
+
+	return nil
+}
+
+// doCmd replyToBot — username бота, на сообщение которого ответили, или пустая строка
+func (p *Processor) doCmd(ctx context.Context, text string, chatID int, from *storage.User, replyToBot string) error {
 	//text = strings.TrimSpace(text)
 
-	log.Printf("got new command '%s' from '%s'", text, userName)
+	name, bot, args := parseCommand(text)
 
-	state, ok := p.states[userName]
+	//Админом или без privacy mode бот видит в группе все сообщения: обычная переписка не должна запускать поиск,
+	//поэтому искать игру он будет только по /add или в ответ на своё сообщение
+	if name == "" && chatID != from.TelegramId {
+		if toMe, err := p.isMe(ctx, replyToBot); err != nil || !toMe {
+			return err
+		}
+	}
+
+	log.Printf("got new command '%s' from '%s' (%d)", text, from.Name, from.TelegramId)
+
+	state, err := p.state(ctx, from.TelegramId)
+	if err != nil {
+		return err
+	}
 
-	if ok && state.Step == AwaitingDateStep {
+	if state != nil && state.Step == AwaitingDateStep {
 		//Если не дата, то очищаем состояние
 		date, err := p.parseDateFromString(text)
 		if err != nil {
-			p.clearState(userName)
+			if err := p.clearState(ctx, from.TelegramId); err != nil {
+				return err
+			}
 		} else {
 			//Иначе проверяем дату
 
 			//Если в прошлом, то не добавляем
 			if date.Before(time.Now()) {
-				return p.tg.SendMessage(ctx, chatID, msgPreviousDate)
+				return p.tg.SendMessage(ctx, chatID, msgPreviousDate, telegram.PlainText)
 			}
 
 			//Иначе добавляем с датой
-			return p.addManualGameWithDate(ctx, chatID, userName, state.GameName, date)
+			return p.addManualGameWithDate(ctx, chatID, from, state.GameName, date)
 		}
 	}
 
-	switch text {
-	case HelpCmd:
-		return p.sendHelp(ctx, chatID)
-	case StartCmd:
-		return p.sendHello(ctx, chatID)
-	case ListCmd:
-		return p.sendGameList(ctx, chatID, userName)
-	case RemoveCmd:
-		return p.sendRemoveList(ctx, chatID, userName)
-	default:
+	if other, err := p.addressedToOther(ctx, bot); err != nil || other {
+		return err
+	}
 
-		if strings.HasPrefix(text, "/") {
-			return p.tg.SendMessage(ctx, chatID, msgUnknownCommand)
-		} else {
-			return p.searchGameList(ctx, text, chatID, userName)
+	if cmd, ok := findCommand(name); ok {
+		return cmd.handle(p, ctx, chatID, from, args)
+	}
+
+	if name != "" {
+		//В группе команда может быть адресована другому боту
+		if chatID != from.TelegramId {
+			return nil
 		}
+		return p.tg.SendMessage(ctx, chatID, msgUnknownCommand, telegram.PlainText)
 	}
+
+	return p.searchGameList(ctx, args, chatID, from)
 }
 
 func (p *Processor) parseDateFromString(strDate string) (time.Time, error) {
 	return time.Parse("02.01.2006", strDate)
 }
 
-func (p *Processor) addManualGameWithDate(ctx context.Context, chatId int, userName string, gameName string, date time.Time) (err error) {
+func (p *Processor) addManualGameWithDate(ctx context.Context, chatId int, from *storage.User, gameName string, date time.Time) (err error) {
 	defer func() { err = e.WrapIfNil("can't add manual", err) }()
 
-	return p.addManualGame(ctx, chatId, userName, gameName, date)
+	return p.addManualGame(ctx, chatId, 0, from, gameName, date)
 }
 
-func (p *Processor) clearState(userName string) {
-	delete(p.states, userName)
-}
+// state возвращает текущее состояние диалога или nil, если его нет или оно устарело
+func (p *Processor) state(ctx context.Context, userId int) (state *storage.State, err error) {
+	defer func() { err = e.WrapIfNil("can't get user state", err) }()
 
-func (p *Processor) addManualGame(ctx context.Context, chatId int, userName string, gameName string, date time.Time) (err error) {
-	defer func() { err = e.WrapIfNil("can't add manual game to storage", err) }()
+	state, err = p.states.GetState(ctx, userId)
+	if errors.Is(err, storage.ErrNoState) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	user := &storage.User{
-		Name:   userName,
-		ChatId: chatId,
+	if time.Since(state.CreatedAt) > stateTTL {
+		return nil, p.clearState(ctx, userId)
 	}
 
+	return state, nil
+}
+
+func (p *Processor) clearState(ctx context.Context, userId int) error {
+	return p.states.RemoveState(ctx, userId)
+}
+
+func (p *Processor) addManualGame(ctx context.Context, chatId int, messageId int, from *storage.User, gameName string, date time.Time) (err error) {
+	defer func() { err = e.WrapIfNil("can't add manual game to storage", err) }()
+
 	game := &storage.Game{
-		Name:   gameName,
-		Source: storage.Manual,
+		Name:        gameName,
+		Source:      storage.Manual,
+		ReleaseDate: date,
 	}
 
 	wishlist := &storage.Wishlist{
-		User: user,
-		Game: game,
+		User:    owner(chatId, from),
+		AddedBy: from,
+		Game:    game,
 	}
 	if !date.IsZero() {
 		wishlist.NotificationDate = date
@@ -105,87 +290,186 @@ func (p *Processor) addManualGame(ctx context.Context, chatId int, userName stri
 		return err
 	}
 	if isExists {
-		return p.tg.SendMessage(ctx, chatId, msgAlreadyExists)
+		return p.reply(ctx, chatId, messageId, gameResult(inChat(chatId, msgAlreadyExists), gameName))
 	}
 
 	if err := p.storage.Add(ctx, wishlist); err != nil {
 		return err
 	}
 
-	p.clearState(userName)
+	if err := p.clearState(ctx, from.TelegramId); err != nil {
+		return err
+	}
 
-	return p.tg.SendMessage(ctx, chatId, msgSaved)
+	return p.reply(ctx, chatId, messageId, gameResult(msgSaved, gameName))
 }
 
-func (p *Processor) sendRemoveList(ctx context.Context, chatId int, userName string) (err error) {
+func (p *Processor) sendRemoveList(ctx context.Context, chatId int, from *storage.User) (err error) {
 	defer func() { err = e.WrapIfNil("can't remove game", err) }()
 
-	user, err := p.storage.GetUserByName(ctx, userName)
-	if err != nil && !errors.Is(err, storage.ErrNoUser) {
+	text, keyboard, err := p.removeList(ctx, owner(chatId, from), 0)
+	if err != nil {
 		return err
 	}
-	if errors.Is(err, storage.ErrNoUser) {
-		return p.tg.SendMessage(ctx, chatId, msgNoWishlist)
+	if keyboard == nil {
+		return p.tg.SendMessage(ctx, chatId, text, telegram.PlainText)
 	}
 
-	wishlist, err := p.storage.GetAll(ctx, user)
-	if err != nil && !errors.Is(err, storage.ErrNoWishlist) {
+	return p.tg.SendMessageWithKeyboard(ctx, chatId, text, telegram.PlainText, keyboard)
+}
+
+func (p *Processor) editRemoveList(ctx context.Context, chatId int, messageId int, from *storage.User, page int) (err error) {
+	defer func() { err = e.WrapIfNil("can't redraw remove list", err) }()
+
+	text, keyboard, err := p.removeList(ctx, owner(chatId, from), page)
+	if err != nil {
 		return err
 	}
-	if errors.Is(err, storage.ErrNoWishlist) {
-		return p.tg.SendMessage(ctx, chatId, msgNoWishlist)
+
+	return p.tg.EditMessageText(ctx, chatId, messageId, text, telegram.PlainText, keyboard)
+}
+
+// removeList возвращает текст и клавиатуру страницы удаления игр; если игр нет, клавиатура nil
+func (p *Processor) removeList(ctx context.Context, from *storage.User, page int) (string, *telegram.InlineKeyboardMarkup, error) {
+	wp, err := p.loadPage(ctx, from, page, removePageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(wp.wishlist) == 0 {
+		return inChat(from.TelegramId, msgNoWishlist), nil, nil
 	}
 
 	var buttons [][]telegram.InlineKeyboardButton
 
-	for _, w := range wishlist {
+	for _, w := range wp.wishlist {
+		//Страница в данных кнопки нужна, чтобы после удаления остаться на ней же
 		button := telegram.InlineKeyboardButton{
 			Text:         fmt.Sprintf("💀%s", w.Game.Name),
-			CallbackData: fmt.Sprintf("remove:%d", w.Id),
+			CallbackData: fmt.Sprintf("remove:%d:%d", w.Id, wp.page),
 		}
 
 		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
 	}
 
-	return p.tg.SendMessageWithKeyboard(ctx, chatId, msgRemoveGameChoice, &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons})
+	if nav := wp.navigation(RemovePageCallback); nav != nil {
+		buttons = append(buttons, nav)
+	}
+
+	return wp.title(msgRemoveGameChoice), &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}, nil
 }
 
-func (p *Processor) sendGameList(ctx context.Context, chatId int, userName string) (err error) {
+func (p *Processor) sendGameList(ctx context.Context, chatId int, from *storage.User) (err error) {
 	defer func() { err = e.WrapIfNil("can't send game list", err) }()
 
-	user, err := p.storage.GetUserByName(ctx, userName)
+	text, keyboard, err := p.listPage(ctx, owner(chatId, from), 0)
+	if err != nil {
+		return err
+	}
+	if keyboard == nil {
+		return p.tg.SendMessage(ctx, chatId, text, telegram.ParseModeHTML)
+	}
+
+	return p.tg.SendMessageWithKeyboard(ctx, chatId, text, telegram.ParseModeHTML, keyboard)
+}
+
+func (p *Processor) editGameList(ctx context.Context, chatId int, messageId int, from *storage.User, page int) (err error) {
+	defer func() { err = e.WrapIfNil("can't redraw game list", err) }()
+
+	text, keyboard, err := p.listPage(ctx, owner(chatId, from), page)
+	if err != nil {
+		return err
+	}
+
+	return p.tg.EditMessageText(ctx, chatId, messageId, text, telegram.ParseModeHTML, keyboard)
+}
+
+func (p *Processor) sendReleasedList(ctx context.Context, chatId int, from *storage.User) (err error) {
+	defer func() { err = e.WrapIfNil("can't send released game list", err) }()
+
+	return p.sendWishlist(ctx, chatId, from, msgReleasedList, msgNoReleased, p.storage.GetReleased)
+}
+
+func (p *Processor) sendUpcomingList(ctx context.Context, chatId int, from *storage.User) (err error) {
+	defer func() { err = e.WrapIfNil("can't send upcoming game list", err) }()
+
+	return p.sendWishlist(ctx, chatId, from, msgUpcomingList, msgNoUpcoming, p.storage.GetUnreleased)
+}
+
+func (p *Processor) sendWishlist(
+	ctx context.Context,
+	chatId int,
+	from *storage.User,
+	header string,
+	emptyMsg string,
+	getWishlist func(ctx context.Context, u *storage.User) ([]storage.Wishlist, error),
+) error {
+	user, err := p.storage.GetUserByTelegramId(ctx, owner(chatId, from).TelegramId)
 	if err != nil && !errors.Is(err, storage.ErrNoUser) {
 		return err
 	}
 	if errors.Is(err, storage.ErrNoUser) {
-		return p.tg.SendMessage(ctx, chatId, msgNoWishlist)
+		return p.tg.SendMessage(ctx, chatId, inChat(chatId, msgNoWishlist), telegram.PlainText)
 	}
 
-	wishlist, err := p.storage.GetAll(ctx, user)
+	wishlist, err := getWishlist(ctx, user)
 	if err != nil && !errors.Is(err, storage.ErrNoWishlist) {
 		return err
 	}
-	if errors.Is(err, storage.ErrNoWishlist) {
-		return p.tg.SendMessage(ctx, chatId, msgNoWishlist)
+	if errors.Is(err, storage.ErrNoWishlist) || len(wishlist) == 0 {
+		return p.tg.SendMessage(ctx, chatId, inChat(chatId, emptyMsg), telegram.PlainText)
 	}
 
-	var builder strings.Builder
-	builder.WriteString(msgGameList)
+	entries := make([]string, 0, len(wishlist))
 
 	for _, w := range wishlist {
-		builder.WriteString(fmt.Sprintf("\n\n🎯 %s", w.Game.Name))
-		if !w.NotificationDate.IsZero() {
-			builder.WriteString(fmt.Sprintf("\n🔔 Дата уведомления: %s", w.NotificationDate.Format("02.01.2006")))
-		}
-		if w.Game.ExternalURL != "" {
-			builder.WriteString(fmt.Sprintf("\n🔗 %s", w.Game.ExternalURL))
+		entries = append(entries, wishlistEntry(w))
+	}
+
+	return p.sendLong(ctx, chatId, header, entries)
+}
+
+// sendLong отправляет список несколькими сообщениями, если он не помещается в одно
+func (p *Processor) sendLong(ctx context.Context, chatId int, header string, entries []string) error {
+	for _, text := range telegram.SplitMessage(header, entries) {
+		if err := p.tg.SendMessage(ctx, chatId, text, telegram.ParseModeHTML); err != nil {
+			return err
 		}
 	}
 
-	return p.tg.SendMessage(ctx, chatId, builder.String())
+	return nil
+}
+
+// wishlistEntry запись списка желаемого для сообщений с ParseModeHTML
+func wishlistEntry(w storage.Wishlist) string {
+	var builder strings.Builder
+
+	builder.WriteString("🎯 " + telegram.Link(w.Game.Name, w.Game.ExternalURL))
+	if !w.Game.ReleaseDate.IsZero() {
+		builder.WriteString("\n📅 Дата выхода: " + telegram.Bold(w.Game.ReleaseDate.Format("02.01.2006")))
+	}
+	if len(w.Platforms) > 0 {
+		builder.WriteString("\n🕹️ Платформы: " + telegram.EscapeHTML(platformNames(w.Platforms)))
+	}
+	if !w.NotificationDate.IsZero() {
+		builder.WriteString("\n🔔 Дата уведомления: " + telegram.Bold(w.NotificationDate.Format("02.01.2006")))
+	}
+	if w.AddedBy != nil && w.AddedBy.Name != "" {
+		builder.WriteString("\n" + msgAddedBy + telegram.EscapeHTML(w.AddedBy.Name))
+	}
+
+	return builder.String()
 }
 
-func (p *Processor) searchGameList(ctx context.Context, text string, chatID int, userName string) (err error) {
+func platformNames(platforms []storage.Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		names = append(names, platform.Name)
+	}
+
+	return strings.Join(names, " | ")
+}
+
+func (p *Processor) searchGameList(ctx context.Context, text string, chatID int, from *storage.User) (err error) {
 	defer func() { err = e.WrapIfNil("can't search game", err) }()
 
 	var res []api.SearchResult
@@ -194,9 +478,16 @@ func (p *Processor) searchGameList(ctx context.Context, text string, chatID int,
 		return err
 	}
 	if errors.Is(err, api.ErrNoSearchResults) {
-		return p.sendNoSearchResults(ctx, text, chatID, userName)
+		return p.sendNoSearchResults(ctx, text, chatID, from)
 	}
 
+	return p.tg.SendMessageWithKeyboard(ctx, chatID, msgGameListChoice, telegram.PlainText, &telegram.InlineKeyboardMarkup{
+		InlineKeyboard: searchButtons(res),
+	})
+}
+
+// searchButtons выбор игры заменяет клавиатуру поиска ответом, а карточка открывается отдельной кнопкой рядом
+func searchButtons(res []api.SearchResult) [][]telegram.InlineKeyboardButton {
 	var buttons [][]telegram.InlineKeyboardButton
 
 	for _, game := range res {
@@ -204,24 +495,26 @@ func (p *Processor) searchGameList(ctx context.Context, text string, chatID int,
 		if !game.FirstReleaseDate.IsZero() {
 			buttonText += fmt.Sprintf(" (%s)", strconv.Itoa(game.FirstReleaseDate.Year()))
 		}
-		button := telegram.InlineKeyboardButton{
-			Text:         buttonText,
-			CallbackData: fmt.Sprintf("select:%d", game.Id),
-		}
-		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
+		buttons = append(buttons, []telegram.InlineKeyboardButton{
+			{Text: buttonText, CallbackData: fmt.Sprintf("%s:%d", SelectCallback, game.Id)},
+			{Text: btnDetails, CallbackData: fmt.Sprintf("%s:%d", DetailsCallback, game.Id)},
+		})
 	}
 
-	return p.tg.SendMessageWithKeyboard(ctx, chatID, msgGameListChoice, &telegram.InlineKeyboardMarkup{
-		InlineKeyboard: buttons,
-	})
+	return buttons
 }
 
-func (p *Processor) sendNoSearchResults(ctx context.Context, text string, chatId int, userName string) (err error) {
+func (p *Processor) sendNoSearchResults(ctx context.Context, text string, chatId int, from *storage.User) (err error) {
 	defer func() { err = e.WrapIfNil("can't send no search results", err) }()
 
-	p.states[userName] = &UserState{
-		GameName: text,
-		Step:     AwaitingDateStep,
+	state := &storage.State{
+		TelegramId: from.TelegramId,
+		Step:       AwaitingDateStep,
+		GameName:   text,
+		CreatedAt:  time.Now(),
+	}
+	if err := p.states.SaveState(ctx, state); err != nil {
+		return err
 	}
 
 	var buttons [][]telegram.InlineKeyboardButton
@@ -232,15 +525,37 @@ func (p *Processor) sendNoSearchResults(ctx context.Context, text string, chatId
 	}
 	buttons = append(buttons, []telegram.InlineKeyboardButton{button})
 
-	return p.tg.SendMessageWithKeyboard(ctx, chatId, msgNoSearchResults, &telegram.InlineKeyboardMarkup{
+	return p.tg.SendMessageWithKeyboard(ctx, chatId, inChat(chatId, msgNoSearchResults), telegram.PlainText, &telegram.InlineKeyboardMarkup{
 		InlineKeyboard: buttons,
 	})
 }
 
+// reply редактирует сообщение с нажатой кнопкой, убирая клавиатуру, или отправляет новое, если messageId не задан
+func (p *Processor) reply(ctx context.Context, chatId int, messageId int, text string) error {
+	return p.replyWithKeyboard(ctx, chatId, messageId, text, nil)
+}
+
+// replyWithKeyboard как reply, но с клавиатурой. Кнопки из inline-режима приходят без сообщения в чате с ботом,
+// на них отвечаем новым сообщением
+func (p *Processor) replyWithKeyboard(ctx context.Context, chatId int, messageId int, text string, keyboard *telegram.InlineKeyboardMarkup) error {
+	if messageId != 0 {
+		return p.tg.EditMessageText(ctx, chatId, messageId, text, telegram.PlainText, keyboard)
+	}
+	if keyboard == nil {
+		return p.tg.SendMessage(ctx, chatId, text, telegram.PlainText)
+	}
+
+	return p.tg.SendMessageWithKeyboard(ctx, chatId, text, telegram.PlainText, keyboard)
+}
+
+func gameResult(msg string, gameName string) string {
+	return fmt.Sprintf("%s\n\n🎮 %s", msg, gameName)
+}
+
 func (p *Processor) sendHelp(ctx context.Context, chatId int) error {
-	return p.tg.SendMessage(ctx, chatId, msgHelp)
+	return p.tg.SendMessage(ctx, chatId, msgHelp, telegram.PlainText)
 }
 
 func (p *Processor) sendHello(ctx context.Context, chatId int) error {
-	return p.tg.SendMessage(ctx, chatId, msgHello)
+	return p.tg.SendMessage(ctx, chatId, msgHello, telegram.PlainText)
 }
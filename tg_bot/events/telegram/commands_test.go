@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"regexp"
+	"testing"
+	"tg_game_wishlist/api"
+)
+
+// Telegram принимает в меню только такие имена команд
+var menuCommandName = regexp.MustCompile(`^/[a-z0-9_]{1,32}$`)
+
+func TestCommands(t *testing.T) {
+	seen := make(map[string]bool)
+
+	for _, cmd := range commands {
+		if !menuCommandName.MatchString(cmd.name) {
+			t.Errorf("command %q can't be registered in menu", cmd.name)
+		}
+		if seen[cmd.name] {
+			t.Errorf("command %q registered twice", cmd.name)
+		}
+		seen[cmd.name] = true
+
+		if cmd.handle == nil {
+			t.Errorf("command %q has no handler", cmd.name)
+		}
+		for _, lang := range menuLanguages {
+			if desc := cmd.descriptions[lang]; len(desc) < 3 || len([]rune(desc)) > 256 {
+				t.Errorf("command %q has bad description for language %q: %q", cmd.name, lang, desc)
+			}
+		}
+	}
+}
+
+// Выбор игры из поиска должен сворачивать клавиатуру поиска, а карточка открываться отдельной кнопкой
+func TestSearchButtons(t *testing.T) {
+	buttons := searchButtons([]api.SearchResult{{Id: 7, Name: "Doom"}})
+
+	if len(buttons) != 1 || len(buttons[0]) != 2 {
+		t.Fatalf("buttons = %+v, want one row with select and details", buttons)
+	}
+	if got := buttons[0][0].CallbackData; got != "select:7" {
+		t.Errorf("game button data = %q, want select:7", got)
+	}
+	if got := buttons[0][1].CallbackData; got != "details:7" {
+		t.Errorf("details button data = %q, want details:7", got)
+	}
+}
@@ -0,0 +1,48 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/events"
+	"tg_game_wishlist/lib/e"
+)
+
+// processChatMember здоровается с группой, в которую добавили бота; у группы сразу появляется общий список желаемого
+func (p *Processor) processChatMember(ctx context.Context, event events.Event) error {
+	meta, err := meta(event)
+	if err != nil {
+		return e.Wrap("can't process chat member", err)
+	}
+
+	//В личном чате статус меняется, когда пользователь блокирует бота или разблокирует его
+	if meta.ChatId == meta.UserId {
+		return nil
+	}
+
+	switch event.Text {
+	case telegram.MemberStatusMember, telegram.MemberStatusAdministrator:
+		if err := p.tg.SendMessage(ctx, meta.ChatId, msgGroupHello, telegram.PlainText); err != nil {
+			return e.Wrap("can't process chat member", err)
+		}
+	case telegram.MemberStatusLeft, telegram.MemberStatusKicked:
+		//Список группы сохраняется: если бота вернут, он продолжит работать с ним
+		log.Printf("removed from chat %d by '%s' (%d)", meta.ChatId, meta.UserName, meta.UserId)
+	}
+
+	return nil
+}
+
+// processChatMigration переносит список желаемого группы, ставшей супергруппой: у супергруппы новый id
+func (p *Processor) processChatMigration(ctx context.Context, event events.Event) error {
+	meta, err := meta(event)
+	if err != nil {
+		return e.Wrap("can't process chat migration", err)
+	}
+
+	if err := p.storage.MigrateChat(ctx, meta.ChatId, meta.MigrateToChatId); err != nil {
+		return e.Wrap("can't process chat migration", err)
+	}
+
+	return nil
+}
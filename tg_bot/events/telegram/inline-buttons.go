@@ -2,4 +2,10 @@ package telegram
 
 const (
 	btnAddGameWithoutDate = "Добавить без уведомления 🔕"
+	btnPrevPage           = "◀️"
+	btnNextPage           = "▶️"
+	btnAddToWishlist      = "➕ Добавить в мой список желаемого"
+	btnAdd                = "➕ Добавить"
+	btnBack               = "⬅️ Назад"
+	btnDetails            = "ℹ️"
 )
@@ -0,0 +1,78 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/lib/e"
+)
+
+// Результаты поиска одинаковы для всех пользователей, поэтому Telegram может кешировать их
+const inlineCacheTime = 300
+
+// answerInline ищет игры по inline-запросу; кнопка в карточке добавляет игру через тот же select, что и обычный поиск
+func (p *Processor) answerInline(ctx context.Context, inlineQueryId string, query string) (err error) {
+	defer func() { err = e.WrapIfNil("can't answer inline query", err) }()
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return p.tg.AnswerInlineQuery(ctx, inlineQueryId, nil, inlineCacheTime)
+	}
+
+	res, err := p.finder.Find(ctx, query)
+	if err != nil && !errors.Is(err, api.ErrNoSearchResults) {
+		return err
+	}
+
+	results := make([]telegram.InlineQueryResultArticle, 0, len(res))
+	for _, game := range res {
+		results = append(results, inlineResult(game))
+	}
+
+	return p.tg.AnswerInlineQuery(ctx, inlineQueryId, results, inlineCacheTime)
+}
+
+func inlineResult(game api.SearchResult) telegram.InlineQueryResultArticle {
+	year := msgNoReleaseYear
+	if !game.FirstReleaseDate.IsZero() {
+		year = strconv.Itoa(game.FirstReleaseDate.Year())
+	}
+
+	text := fmt.Sprintf("🎮 %s\n📅 %s", telegram.Link(game.Name, game.URL), telegram.Bold(year))
+
+	return telegram.InlineQueryResultArticle{
+		Type:        telegram.ArticleResult,
+		Id:          strconv.Itoa(game.Id),
+		Title:       game.Name,
+		Description: year,
+		URL:         game.URL,
+		InputMessageContent: telegram.InputMessageContent{
+			MessageText: text,
+			ParseMode:   telegram.ParseModeHTML,
+		},
+		ReplyMarkup: &telegram.InlineKeyboardMarkup{
+			InlineKeyboard: [][]telegram.InlineKeyboardButton{{
+				{
+					Text:         btnAddToWishlist,
+					CallbackData: fmt.Sprintf("%s:%d", SelectCallback, game.Id),
+				},
+			}},
+		},
+	}
+}
+
+// startBotFirst текст подсказки для того, кто нажал кнопку из inline-режима, не запустив бота
+func (p *Processor) startBotFirst(ctx context.Context) string {
+	name, err := p.botName(ctx)
+	if err != nil {
+		log.Printf("can't get bot name: %s", err)
+		return fmt.Sprintf(msgStartBotFirst, "ботом")
+	}
+
+	return fmt.Sprintf(msgStartBotFirst, "@"+name)
+}
@@ -2,16 +2,26 @@ package telegram
 
 const msgHelp = `Я могу сохранять и отслеживать твой список желаемых видеоигр.
 
-Для того, чтобы найти желаемую игру, просто введи её название! 
+Для того, чтобы найти желаемую игру, просто введи её название или отправь /add и название! 
 Затем тебе нужно выбрать игру из результатов поиска. 
 Если игра ещё не вышла, то я отправлю тебе уведомление в день релиза!
 
 Если хочешь посмотреть свой список желаемого, отправь мне команду /list.
+Уже вышедшие игры покажет команда /released, а ожидаемые — /upcoming.
 
-Ты можешь удалить игры из списка желаемого, для этого отправь команду /remove.`
+Ты можешь удалить игры из списка желаемого, для этого отправь команду /remove.
+
+Команда /share даст ссылку, по которой друзья увидят твой список, а /unshare её отзовёт.`
 
 const msgHello = "Привет! 👾\n\n" + msgHelp
 
+const msgGroupHello = `Привет! 👾
+
+Теперь у этого чата есть общий список желаемого: любой участник может добавить в него игру, а в день релиза я напишу сюда.
+
+Чтобы найти игру, отправь /add и её название или ответь на это сообщение её названием.
+Список покажет команда /list, уже вышедшие игры — /released, ожидаемые — /upcoming, удалить игру можно через /remove.`
+
 const (
 	msgUnknownCommand      = "Неизвестная команда 🤔"
 	msgNoWishlist          = "У тебя нет игр в списке желаемого 🙊"
@@ -21,8 +31,53 @@ const (
 	msgIncorrectDateFormat = "Формат даты не подходит, нужен ДД.ММ.ГГГГ"
 	msgPreviousDate        = "Ой, ты ввёл прошедшую дату 😅\nК сожалению, машина времени ещё в разработке ⏳, и отправить уведомление в прошлое не получится 🚀\n\nМожешь ввести дату в будущем 🔮, или найти новую игру 🔍"
 	msgGameList            = "Твой список желаемого 🛒"
+	msgReleasedList        = "Уже вышли 🕹️"
+	msgUpcomingList        = "Ожидаются ⏳"
+	msgNoReleased          = "В твоём списке желаемого пока нет вышедших игр 🙊"
+	msgNoUpcoming          = "В твоём списке желаемого нет ожидаемых игр 🙊"
 	msgGameListChoice      = "Выбери игру из найденных 🫵"
 	msgRemoveGameChoice    = "Выбери игру для удаления из списка желаемого ☠️"
 	msgRemoved             = "Удалено! 👌"
+	msgPage                = "Страница %d из %d"
+	msgNoReleaseYear       = "Дата выхода неизвестна"
+	msgDeveloper           = "🏢 Разработчик: "
+	msgGenres              = "🏷️ Жанры: "
+	msgReleaseDate         = "📅 Дата выхода: "
+	msgAddedBy             = "👤 Добавлено: "
+	msgShareLink           = "Ссылка на список желаемого 🔗\nПо ней список можно посмотреть, но не изменить. Отозвать все ссылки — /unshare\n\n"
+	msgUnshared            = "Ссылки на список желаемого отозваны 🔒"
+	msgShareNotFound       = "Ссылка недействительна: её отозвали или она неверная 🤷"
+	msgSharedList          = "Список желаемого по ссылке 👀"
+	msgSharedEmpty         = "В этом списке желаемого пока нет игр 🙊"
 	msgPlatformDateChoice  = "Игра с разными датами на платформах 🕹️\nВыбери одну, в день, когда хочешь получить уведомление 🕓"
+	msgAddUsage            = "Напиши название игры после команды, например: /add Hollow Knight"
+	msgStartBotFirst       = "Я не могу написать тебе первым 🙈\nОткрой чат с %s, нажми «Старт» и выбери игру ещё раз"
+)
+
+// Варианты сообщений для общего списка группы
+const (
+	msgGroupNoWishlist      = "В списке желаемого этого чата пока нет игр 🙊"
+	msgGroupAlreadyExists   = "Эта игра уже есть в списке желаемого чата 🤗"
+	msgGroupGameList        = "Список желаемого чата 🛒"
+	msgGroupNoReleased      = "В списке желаемого чата пока нет вышедших игр 🙊"
+	msgGroupNoUpcoming      = "В списке желаемого чата нет ожидаемых игр 🙊"
+	msgGroupNoSearchResults = "Игры с таким названием не найдены 🥲\n\nМожно добавить эту игру без уведомления (через кнопочку) 🔕\n\nИли ответь на это сообщение её датой (ДД.ММ.ГГГГ), чтобы я написал сюда о выходе игры в этот день 🔔"
 )
+
+var groupMessages = map[string]string{
+	msgNoWishlist:      msgGroupNoWishlist,
+	msgAlreadyExists:   msgGroupAlreadyExists,
+	msgGameList:        msgGroupGameList,
+	msgNoReleased:      msgGroupNoReleased,
+	msgNoUpcoming:      msgGroupNoUpcoming,
+	msgNoSearchResults: msgGroupNoSearchResults,
+}
+
+// inChat текст сообщения для чата: в группе (id групп в Telegram отрицательные) список общий, и обращение «у тебя» не подходит
+func inChat(chatId int, msg string) string {
+	if group, ok := groupMessages[msg]; ok && chatId < 0 {
+		return group
+	}
+
+	return msg
+}
@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/storage"
+)
+
+const (
+	listPageSize   = 5
+	removePageSize = 10
+)
+
+// wishlistPage страница списка желаемого; page и pages уже приведены к существующим страницам
+type wishlistPage struct {
+	wishlist []storage.Wishlist
+	page     int
+	pages    int
+}
+
+// loadPage загружает страницу списка желаемого; если список пуст, wishlist пустой
+func (p *Processor) loadPage(ctx context.Context, from *storage.User, page int, size int) (*wishlistPage, error) {
+	user, err := p.storage.GetUserByTelegramId(ctx, from.TelegramId)
+	if err != nil && !errors.Is(err, storage.ErrNoUser) {
+		return nil, err
+	}
+	if errors.Is(err, storage.ErrNoUser) {
+		return &wishlistPage{}, nil
+	}
+
+	count, err := p.storage.Count(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return &wishlistPage{}, nil
+	}
+
+	//После удаления последней игры на странице показывается предыдущая
+	pages := (count + size - 1) / size
+	page = max(0, min(page, pages-1))
+
+	wishlist, err := p.storage.GetPage(ctx, user, size, page*size)
+	if err != nil && !errors.Is(err, storage.ErrNoWishlist) {
+		return nil, err
+	}
+
+	return &wishlistPage{wishlist: wishlist, page: page, pages: pages}, nil
+}
+
+// navigation возвращает кнопки перехода между страницами или nil, если страница одна
+func (wp *wishlistPage) navigation(callback string) []telegram.InlineKeyboardButton {
+	var buttons []telegram.InlineKeyboardButton
+
+	if wp.page > 0 {
+		buttons = append(buttons, telegram.InlineKeyboardButton{
+			Text:         btnPrevPage,
+			CallbackData: fmt.Sprintf("%s:%d", callback, wp.page-1),
+		})
+	}
+	if wp.page < wp.pages-1 {
+		buttons = append(buttons, telegram.InlineKeyboardButton{
+			Text:         btnNextPage,
+			CallbackData: fmt.Sprintf("%s:%d", callback, wp.page+1),
+		})
+	}
+
+	return buttons
+}
+
+func (wp *wishlistPage) title(header string) string {
+	if wp.pages <= 1 {
+		return header
+	}
+
+	return fmt.Sprintf("%s\n"+msgPage, header, wp.page+1, wp.pages)
+}
+
+// fromFinder игра найдена в источнике, с которым сейчас работает бот, и её ExternalId можно передать в finder
+func (p *Processor) fromFinder(game *storage.Game) bool {
+	return game.ExternalId != 0 && game.Source == p.finder.Source()
+}
+
+// listPage возвращает текст и клавиатуру страницы /list; если кнопок нет, клавиатура nil
+func (p *Processor) listPage(ctx context.Context, from *storage.User, page int) (string, *telegram.InlineKeyboardMarkup, error) {
+	wp, err := p.loadPage(ctx, from, page, listPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(wp.wishlist) == 0 {
+		return inChat(from.TelegramId, msgNoWishlist), nil, nil
+	}
+
+	entries := make([]string, 0, len(wp.wishlist))
+	for _, w := range wp.wishlist {
+		entries = append(entries, wishlistEntry(w))
+	}
+	text := wp.title(inChat(from.TelegramId, msgGameList)) + "\n\n" + strings.Join(entries, "\n\n")
+
+	var buttons [][]telegram.InlineKeyboardButton
+
+	//Карточку можно открыть только для игр, найденных в текущем источнике поиска: id разных источников пересекаются
+	for _, w := range wp.wishlist {
+		if !p.fromFinder(w.Game) {
+			continue
+		}
+
+		button := telegram.InlineKeyboardButton{
+			Text:         fmt.Sprintf("ℹ️ %s", w.Game.Name),
+			CallbackData: fmt.Sprintf("%s:%d:%s", DetailsCallback, w.Game.ExternalId, cardFromList),
+		}
+		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
+	}
+
+	if nav := wp.navigation(ListPageCallback); nav != nil {
+		buttons = append(buttons, nav)
+	}
+
+	if len(buttons) == 0 {
+		return text, nil, nil
+	}
+
+	return text, &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}, nil
+}
@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// sharePrefix отличает ссылку на список от других payload команды /start
+const sharePrefix = "w_"
+
+// shareTokenBytes 16 случайных байт — 22 символа в base64, payload /start ограничен 64 символами
+const shareTokenBytes = 16
+
+func (p *Processor) sendShareLink(ctx context.Context, chatId int, from *storage.User) (err error) {
+	defer func() { err = e.WrapIfNil("can't share wishlist", err) }()
+
+	//Делиться можно только существующим списком
+	user, err := p.storage.GetUserByTelegramId(ctx, owner(chatId, from).TelegramId)
+	if err != nil && !errors.Is(err, storage.ErrNoUser) {
+		return err
+	}
+	if errors.Is(err, storage.ErrNoUser) {
+		return p.tg.SendMessage(ctx, chatId, inChat(chatId, msgNoWishlist), telegram.PlainText)
+	}
+
+	botName, err := p.botName(ctx)
+	if err != nil {
+		return err
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return err
+	}
+
+	share := &storage.Share{
+		Token:      token,
+		TelegramId: user.TelegramId,
+		CreatedAt:  time.Now(),
+	}
+	if err := p.shares.SaveShare(ctx, share); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", botName, sharePrefix, token)
+
+	return p.tg.SendMessage(ctx, chatId, msgShareLink+link, telegram.PlainText)
+}
+
+func (p *Processor) unshare(ctx context.Context, chatId int, from *storage.User) (err error) {
+	defer func() { err = e.WrapIfNil("can't unshare wishlist", err) }()
+
+	if err := p.shares.RemoveShares(ctx, owner(chatId, from).TelegramId); err != nil {
+		return err
+	}
+
+	return p.tg.SendMessage(ctx, chatId, msgUnshared, telegram.PlainText)
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", e.Wrap("can't generate share token", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// botName username бота запрашивается один раз: для ссылок и команд вида /list@bot
+func (p *Processor) botName(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.username != "" {
+		return p.username, nil
+	}
+
+	me, err := p.tg.Me(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.username = me.Username
+
+	return p.username, nil
+}
+
+func (p *Processor) sendSharedList(ctx context.Context, chatId int, token string) (err error) {
+	defer func() { err = e.WrapIfNil("can't send shared list", err) }()
+
+	text, keyboard, err := p.sharedPage(ctx, token, 0)
+	if err != nil {
+		return err
+	}
+	if keyboard == nil {
+		return p.tg.SendMessage(ctx, chatId, text, telegram.ParseModeHTML)
+	}
+
+	return p.tg.SendMessageWithKeyboard(ctx, chatId, text, telegram.ParseModeHTML, keyboard)
+}
+
+// sharedPageCallback листает чужой список: shared_page:<token>:<page>
+func (p *Processor) sharedPageCallback(ctx context.Context, callbackId string, text string, chatID int, messageId int) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process shared page callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+	parts, err := callbackParts(text, 3)
+	if err != nil {
+		return err
+	}
+
+	page, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return err
+	}
+
+	msg, keyboard, err := p.sharedPage(ctx, parts[1], page)
+	if err != nil {
+		return err
+	}
+
+	return p.tg.EditMessageText(ctx, chatID, messageId, msg, telegram.ParseModeHTML, keyboard)
+}
+
+// sharedAddCallback добавляет игру из чужого списка в свой; чужой список остаётся на экране, ответ приходит новым сообщением
+func (p *Processor) sharedAddCallback(ctx context.Context, callbackId string, text string, chatID int, from *storage.User) (err error) {
+	defer func() {
+		err = e.WrapIfNil("can't process shared add callback", err)
+		p.tg.AnswerCallBack(ctx, callbackId, "", false)
+	}()
+	parts, err := callbackParts(text, 2)
+	if err != nil {
+		return err
+	}
+
+	gameId, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return err
+	}
+
+	return p.selectGame(ctx, gameId, chatID, 0, from)
+}
+
+// sharedPage страница чужого списка только для просмотра; изменить его нельзя, можно лишь добавить игру к себе.
+// Если ссылку отозвали, клавиатура nil
+func (p *Processor) sharedPage(ctx context.Context, token string, page int) (string, *telegram.InlineKeyboardMarkup, error) {
+	share, err := p.shares.GetShare(ctx, token)
+	if err != nil && !errors.Is(err, storage.ErrNoShare) {
+		return "", nil, err
+	}
+	if errors.Is(err, storage.ErrNoShare) {
+		return msgShareNotFound, nil, nil
+	}
+
+	wp, err := p.loadPage(ctx, &storage.User{TelegramId: share.TelegramId}, page, listPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(wp.wishlist) == 0 {
+		return msgSharedEmpty, nil, nil
+	}
+
+	entries := make([]string, 0, len(wp.wishlist))
+	for _, w := range wp.wishlist {
+		entries = append(entries, wishlistEntry(w))
+	}
+	text := wp.title(msgSharedList) + "\n\n" + strings.Join(entries, "\n\n")
+
+	var buttons [][]telegram.InlineKeyboardButton
+
+	//Добавить к себе можно только игры из текущего источника поиска
+	for _, w := range wp.wishlist {
+		if !p.fromFinder(w.Game) {
+			continue
+		}
+
+		button := telegram.InlineKeyboardButton{
+			Text:         fmt.Sprintf("➕ %s", w.Game.Name),
+			CallbackData: fmt.Sprintf("%s:%d", SharedAddCallback, w.Game.ExternalId),
+		}
+		buttons = append(buttons, []telegram.InlineKeyboardButton{button})
+	}
+
+	if nav := wp.navigation(SharedPageCallback + ":" + token); nav != nil {
+		buttons = append(buttons, nav)
+	}
+
+	if len(buttons) == 0 {
+		return text, nil, nil
+	}
+
+	return text, &telegram.InlineKeyboardMarkup{InlineKeyboard: buttons}, nil
+}
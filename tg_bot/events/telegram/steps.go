@@ -1,5 +1,10 @@
 package telegram
 
+import "time"
+
 const (
 	AwaitingDateStep = "awaiting_date"
 )
+
+// Незавершённый диалог старше этого времени считается брошенным
+const stateTTL = 24 * time.Hour
@@ -3,6 +3,8 @@ package telegram
 import (
 	"context"
 	"errors"
+	"log"
+	"sync"
 	"tg_game_wishlist/api"
 	"tg_game_wishlist/clients/telegram"
 	"tg_game_wishlist/events"
@@ -10,26 +12,38 @@ import (
 	"tg_game_wishlist/storage"
 )
 
-type UserState struct {
-	GameName string
-	Step     string
-}
-
 type Processor struct {
-	tg      *telegram.Client
+	tg      *telegram.Queue
 	finder  api.Finder
 	storage storage.Storage
-	states  map[string]*UserState
+	states  storage.StateStore
+	shares  storage.ShareStore
+
+	mu sync.Mutex
+	//username бота для ссылок, загружается при первой необходимости
+	username string
 }
 
 type Fetcher struct {
-	tg     *telegram.Client
-	offset int
+	tg      *telegram.Client
+	updates storage.UpdateStore
+	// offset следующего запроса и offset, который будет сохранён после обработки пачки
+	offset       int
+	nextOffset   int
+	offsetLoaded bool
 }
 
 type Meta struct {
+	UpdateId int
 	ChatId   int
-	UserName string
+	// MessageId сообщение с нажатой inline-кнопкой, для обычных сообщений не заполняется
+	MessageId int
+	UserId    int
+	UserName  string
+	// MigrateToChatId новый id группы, ставшей супергруппой
+	MigrateToChatId int
+	// ReplyToBot username бота, на сообщение которого ответили
+	ReplyToBot string
 }
 
 var (
@@ -37,18 +51,20 @@ var (
 	ErrUnknownMetaType  = errors.New("unknown meta type")
 )
 
-func NewProcessor(client *telegram.Client, finder api.Finder, storage storage.Storage) *Processor {
+func NewProcessor(client *telegram.Queue, finder api.Finder, storage storage.Storage, states storage.StateStore, shares storage.ShareStore) *Processor {
 	return &Processor{
 		tg:      client,
 		finder:  finder,
 		storage: storage,
-		states:  make(map[string]*UserState),
+		states:  states,
+		shares:  shares,
 	}
 }
 
-func NewFetcher(client *telegram.Client) *Fetcher {
+func NewFetcher(client *telegram.Client, updates storage.UpdateStore) *Fetcher {
 	return &Fetcher{
-		tg: client,
+		tg:      client,
+		updates: updates,
 	}
 }
 
@@ -58,6 +74,12 @@ func (p *Processor) Process(ctx context.Context, event events.Event) error {
 		return p.processMessage(ctx, event)
 	case events.CallbackQuery:
 		return p.processCallbackQuery(ctx, event)
+	case events.InlineQuery:
+		return p.processInlineQuery(ctx, event)
+	case events.ChatMember:
+		return p.processChatMember(ctx, event)
+	case events.ChatMigration:
+		return p.processChatMigration(ctx, event)
 	default:
 		return e.Wrap("can't process event", ErrUnknownEventType)
 	}
@@ -69,7 +91,7 @@ func (p *Processor) processMessage(ctx context.Context, event events.Event) erro
 		return e.Wrap("can't process message", err)
 	}
 
-	if err := p.doCmd(ctx, event.Text, meta.ChatId, meta.UserName); err != nil {
+	if err := p.doCmd(ctx, event.Text, meta.ChatId, sender(meta), meta.ReplyToBot); err != nil {
 		return e.Wrap("can't process message", err)
 	}
 
@@ -82,13 +104,21 @@ func (p *Processor) processCallbackQuery(ctx context.Context, event events.Event
 		return e.Wrap("can't process callback query", err)
 	}
 
-	if err := p.doCallback(ctx, event.Id, event.Text, meta.ChatId, meta.UserName); err != nil {
+	if err := p.doCallback(ctx, event.Id, event.Text, meta.ChatId, meta.MessageId, sender(meta)); err != nil {
 		return e.Wrap("can't process callback query", err)
 	}
 
 	return nil
 }
 
+func (p *Processor) processInlineQuery(ctx context.Context, event events.Event) error {
+	if err := p.answerInline(ctx, event.Id, event.Text); err != nil {
+		return e.Wrap("can't process inline query", err)
+	}
+
+	return nil
+}
+
 func meta(event events.Event) (Meta, error) {
 	res, ok := event.Meta.(Meta)
 	if !ok {
@@ -98,27 +128,107 @@ func meta(event events.Event) (Meta, error) {
 	return res, nil
 }
 
-func (f *Fetcher) Fetch(ctx context.Context, limit int, timeout int) ([]events.Event, error) {
+// Пользователь определяется по telegram id, username хранится только для отображения.
+// Чат пользователя — всегда личный: id личного чата совпадает с id пользователя, даже если он пишет из группы
+func sender(meta Meta) *storage.User {
+	return &storage.User{
+		TelegramId: meta.UserId,
+		Name:       meta.UserName,
+		ChatId:     meta.UserId,
+	}
+}
+
+// owner владелец списка желаемого, с которым работают в чате: в группе это сама группа, в личном чате — пользователь
+func owner(chatId int, from *storage.User) *storage.User {
+	if chatId == from.TelegramId {
+		return from
+	}
+
+	return &storage.User{
+		TelegramId: chatId,
+		ChatId:     chatId,
+	}
+}
+
+// displayName имя для отображения: username есть не у всех пользователей
+func displayName(from telegram.From) string {
+	if from.Username != "" {
+		return from.Username
+	}
+
+	return from.FirstName
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, limit int, timeout int) (res []events.Event, err error) {
+	defer func() { err = e.WrapIfNil("can't get events", err) }()
+
+	if !f.offsetLoaded {
+		if f.offset, err = f.updates.GetOffset(ctx); err != nil {
+			return nil, err
+		}
+		f.nextOffset = f.offset
+		f.offsetLoaded = true
+	}
+
 	updates, err := f.tg.Updates(ctx, f.offset, limit, timeout)
 	if err != nil {
-		return nil, e.Wrap("can't get events", err)
+		return nil, err
 	}
 
 	if len(updates) == 0 {
 		return nil, nil
 	}
 
-	res := make([]events.Event, 0, len(updates))
+	f.nextOffset = updates[len(updates)-1].Id + 1
+
+	res = make([]events.Event, 0, len(updates))
 
 	for _, u := range updates {
+		//После падения Telegram может прислать уже обработанные обновления
+		processed, err := f.updates.IsProcessed(ctx, u.Id)
+		if err != nil {
+			return nil, err
+		}
+		if processed {
+			log.Printf("skip already processed update %d", u.Id)
+			continue
+		}
+
 		res = append(res, event(u))
 	}
 
-	f.offset = updates[len(updates)-1].Id + 1
-
 	return res, nil
 }
 
+func (f *Fetcher) Ack(ctx context.Context, event events.Event) error {
+	meta, err := meta(event)
+	if err != nil {
+		return e.Wrap("can't ack event", err)
+	}
+
+	if err := f.updates.MarkProcessed(ctx, meta.UpdateId); err != nil {
+		return e.Wrap("can't ack event", err)
+	}
+
+	return nil
+}
+
+func (f *Fetcher) Commit(ctx context.Context) error {
+	if f.nextOffset == f.offset {
+		return nil
+	}
+
+	//Следующий запрос идёт с новым offset, даже если сохранить его не удалось:
+	//после перезапуска повторно полученные обновления отсеются как обработанные
+	f.offset = f.nextOffset
+
+	if err := f.updates.SaveOffset(ctx, f.offset); err != nil {
+		return e.Wrap("can't commit events", err)
+	}
+
+	return nil
+}
+
 func event(upd telegram.Update) events.Event {
 	updType := fetchType(upd)
 
@@ -131,32 +241,92 @@ func event(upd telegram.Update) events.Event {
 	switch updType {
 	case events.Message:
 		res.Meta = Meta{
-			ChatId:   upd.Message.Chat.Id,
-			UserName: upd.Message.From.Username,
+			UpdateId:   upd.Id,
+			ChatId:     upd.Message.Chat.Id,
+			UserId:     upd.Message.From.Id,
+			UserName:   displayName(upd.Message.From),
+			ReplyToBot: replyToBot(upd.Message),
+		}
+	case events.ChatMigration:
+		res.Meta = Meta{
+			UpdateId:        upd.Id,
+			ChatId:          upd.Message.Chat.Id,
+			UserId:          upd.Message.From.Id,
+			UserName:        displayName(upd.Message.From),
+			MigrateToChatId: upd.Message.MigrateToChatId,
+		}
+	case events.ChatMember:
+		res.Meta = Meta{
+			UpdateId: upd.Id,
+			ChatId:   upd.MyChatMember.Chat.Id,
+			UserId:   upd.MyChatMember.From.Id,
+			UserName: displayName(upd.MyChatMember.From),
 		}
 	case events.CallbackQuery:
+		res.Meta = callbackMeta(upd)
+	case events.InlineQuery:
 		res.Meta = Meta{
-			ChatId:   upd.CallbackQuery.Message.Chat.Id,
-			UserName: upd.CallbackQuery.From.Username,
+			UpdateId: upd.Id,
+			UserId:   upd.InlineQuery.From.Id,
+			UserName: displayName(upd.InlineQuery.From),
 		}
 	case events.Unknown:
-		res.Meta = nil
+		res.Meta = Meta{
+			UpdateId: upd.Id,
+		}
 	}
 
 	return res
 }
 
-func fetchId(upd telegram.Update) string {
-	if upd.CallbackQuery == nil {
+func replyToBot(msg *telegram.IncomingMessage) string {
+	if msg.ReplyToMessage == nil || !msg.ReplyToMessage.From.IsBot {
 		return ""
 	}
 
-	return upd.CallbackQuery.Id
+	return msg.ReplyToMessage.From.Username
+}
+
+func callbackMeta(upd telegram.Update) Meta {
+	cq := upd.CallbackQuery
+
+	//У сообщения, отправленного через inline-режим, нет чата с ботом:
+	//ответ уходит в личный чат с пользователем, id которого совпадает с id чата
+	if cq.Message == nil {
+		return Meta{
+			UpdateId: upd.Id,
+			ChatId:   cq.From.Id,
+			UserId:   cq.From.Id,
+			UserName: displayName(cq.From),
+		}
+	}
+
+	return Meta{
+		UpdateId:  upd.Id,
+		ChatId:    cq.Message.Chat.Id,
+		MessageId: cq.Message.MessageId,
+		UserId:    cq.From.Id,
+		UserName:  displayName(cq.From),
+	}
+}
+
+func fetchId(upd telegram.Update) string {
+	if upd.CallbackQuery != nil {
+		return upd.CallbackQuery.Id
+	} else if upd.InlineQuery != nil {
+		return upd.InlineQuery.Id
+	}
+
+	return ""
 }
 
 func fetchText(upd telegram.Update) string {
 	if upd.CallbackQuery != nil {
 		return upd.CallbackQuery.Data
+	} else if upd.InlineQuery != nil {
+		return upd.InlineQuery.Query
+	} else if upd.MyChatMember != nil {
+		return upd.MyChatMember.NewChatMember.Status
 	} else if upd.Message == nil {
 		return ""
 	}
@@ -167,8 +337,14 @@ func fetchText(upd telegram.Update) string {
 func fetchType(upd telegram.Update) events.Type {
 	if upd.CallbackQuery != nil {
 		return events.CallbackQuery
+	} else if upd.InlineQuery != nil {
+		return events.InlineQuery
+	} else if upd.MyChatMember != nil {
+		return events.ChatMember
 	} else if upd.Message == nil {
 		return events.Unknown
+	} else if upd.Message.MigrateToChatId != 0 {
+		return events.ChatMigration
 	}
 
 	return events.Message
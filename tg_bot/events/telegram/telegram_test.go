@@ -0,0 +1,179 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/events"
+)
+
+func readUpdate(t *testing.T, name string) telegram.Update {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("can't read recorded update: %v", err)
+	}
+
+	var upd telegram.Update
+	if err := json.Unmarshal(data, &upd); err != nil {
+		t.Fatalf("can't unmarshal recorded update: %v", err)
+	}
+
+	return upd
+}
+
+func TestEvent(t *testing.T) {
+	tests := []struct {
+		file     string
+		wantType events.Type
+		wantId   string
+		wantText string
+		wantMeta Meta
+	}{
+		{
+			file:     "inline_query.json",
+			wantType: events.InlineQuery,
+			wantId:   "837465928374659283",
+			wantText: "hollow knight",
+			wantMeta: Meta{UpdateId: 512345003, UserId: 123456789, UserName: "ivan_gamer"},
+		},
+		{
+			//Кнопка под сообщением из inline-режима: ответ уходит в личный чат
+			file:     "inline_callback_query.json",
+			wantType: events.CallbackQuery,
+			wantId:   "4382bfdwdsb323b2e1",
+			wantText: "select:14593",
+			wantMeta: Meta{UpdateId: 512345004, ChatId: 123456789, UserId: 123456789, UserName: "ivan_gamer"},
+		},
+		{
+			file:     "my_chat_member.json",
+			wantType: events.ChatMember,
+			wantText: "member",
+			wantMeta: Meta{UpdateId: 512345005, ChatId: -4012345678, UserId: 123456789, UserName: "ivan_gamer"},
+		},
+		{
+			//У пользователя без username показывается имя
+			file:     "migrate_to_chat.json",
+			wantType: events.ChatMigration,
+			wantMeta: Meta{UpdateId: 512345006, ChatId: -4012345678, UserId: 123456789, UserName: "Ivan", MigrateToChatId: -1002012345678},
+		},
+		{
+			//В группе поиск запускает ответ на сообщение бота
+			file:     "group_reply.json",
+			wantType: events.Message,
+			wantText: "hollow knight",
+			wantMeta: Meta{UpdateId: 512345007, ChatId: -1002012345678, UserId: 123456789, UserName: "ivan_gamer", ReplyToBot: "game_wishlist_bot"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := event(readUpdate(t, tt.file))
+
+			if got.Type != tt.wantType || got.Id != tt.wantId || got.Text != tt.wantText {
+				t.Fatalf("event = %+v, want type %d, id %q, text %q", got, tt.wantType, tt.wantId, tt.wantText)
+			}
+			if got.Meta != tt.wantMeta {
+				t.Fatalf("event meta = %+v, want %+v", got.Meta, tt.wantMeta)
+			}
+		})
+	}
+}
+
+func TestOwner(t *testing.T) {
+	from := sender(Meta{ChatId: -4012345678, UserId: 123456789, UserName: "ivan_gamer"})
+
+	//Пользователь из группы остаётся привязан к личному чату
+	if from.ChatId != 123456789 {
+		t.Fatalf("sender ChatId = %d, want private chat 123456789", from.ChatId)
+	}
+
+	if got := owner(123456789, from); got != from {
+		t.Fatalf("owner in private chat = %+v, want sender", got)
+	}
+
+	got := owner(-4012345678, from)
+	if got.TelegramId != -4012345678 || got.ChatId != -4012345678 {
+		t.Fatalf("owner in group = %+v, want group -4012345678", got)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	for _, tc := range []struct {
+		text, name, bot, args string
+	}{
+		{"/list", "/list", "", ""},
+		{"/list@game_wishlist_bot", "/list", "game_wishlist_bot", ""},
+		{"/remove@other_bot", "/remove", "other_bot", ""},
+		{"/start w_abc-DEF_1", "/start", "", "w_abc-DEF_1"},
+		{"/start@game_wishlist_bot  w_abc ", "/start", "game_wishlist_bot", "w_abc"},
+		//Поиск игры, а не команда другому боту
+		{"Doom@home", "", "", "Doom@home"},
+		{"halo@xbox 360", "", "", "halo@xbox 360"},
+	} {
+		name, bot, args := parseCommand(tc.text)
+		if name != tc.name || bot != tc.bot || args != tc.args {
+			t.Errorf("parseCommand(%q) = %q, %q, %q, want %q, %q, %q", tc.text, name, bot, args, tc.name, tc.bot, tc.args)
+		}
+	}
+}
+
+func TestAddressedToOther(t *testing.T) {
+	p := &Processor{username: "Game_Wishlist_Bot"}
+
+	for text, want := range map[string]bool{
+		"/list":                   false,
+		"/list@game_wishlist_bot": false,
+		"/list@other_bot":         true,
+		"Doom@home":               false,
+	} {
+		_, bot, _ := parseCommand(text)
+		got, err := p.addressedToOther(context.Background(), bot)
+		if err != nil || got != want {
+			t.Errorf("addressedToOther for %q = %v, %v, want %v", text, got, err, want)
+		}
+	}
+}
+
+func TestGroupChatterIgnored(t *testing.T) {
+	//Без хранилища и поиска: до них дело доходить не должно
+	p := &Processor{username: "game_wishlist_bot"}
+	from := sender(Meta{ChatId: -4012345678, UserId: 123456789, UserName: "ivan_gamer"})
+
+	for _, replyToBot := range []string{"", "other_bot"} {
+		if err := p.doCmd(context.Background(), "hollow knight", -4012345678, from, replyToBot); err != nil {
+			t.Fatalf("doCmd in group with reply to %q: %v", replyToBot, err)
+		}
+	}
+}
+
+func TestInChat(t *testing.T) {
+	if got := inChat(123456789, msgNoWishlist); got != msgNoWishlist {
+		t.Fatalf("inChat in private chat = %q, want %q", got, msgNoWishlist)
+	}
+	if got := inChat(-4012345678, msgNoWishlist); got != msgGroupNoWishlist {
+		t.Fatalf("inChat in group = %q, want %q", got, msgGroupNoWishlist)
+	}
+	if got := inChat(-4012345678, msgSaved); got != msgSaved {
+		t.Fatalf("inChat in group for common message = %q, want %q", got, msgSaved)
+	}
+}
+
+func TestCallbackParts(t *testing.T) {
+	//Данные кнопки приходят от клиента, и их можно подделать
+	for _, text := range []string{RemoveCallback, ListPageCallback, DetailsCallback, SharedPageCallback + ":token"} {
+		if _, err := callbackParts(text, 3); !errors.Is(err, ErrBadCallbackData) {
+			t.Errorf("callbackParts(%q) = %v, want ErrBadCallbackData", text, err)
+		}
+	}
+
+	parts, err := callbackParts("remove:12:3", 2)
+	if err != nil || len(parts) != 3 || parts[1] != "12" {
+		t.Fatalf("callbackParts = %q, %v", parts, err)
+	}
+}
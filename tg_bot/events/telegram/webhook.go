@@ -0,0 +1,209 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/events"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	webhookQueueSize  = 100
+	//Telegram повторяет доставку не дольше суток, более старые отметки об обработке не нужны
+	processedTTL  = 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+// WebhookFetcher принимает обновления от Telegram по HTTP и отдаёт их через Fetch,
+// поэтому может использоваться вместо Fetcher с тем же consumer и processor.
+// Telegram получает ответ только после обработки пачки с его обновлением (Commit),
+// поэтому обновления, не обработанные из-за падения, он доставит повторно
+type WebhookFetcher struct {
+	secretToken string
+	updates     storage.UpdateStore
+	queue       chan webhookUpdate
+
+	mu sync.Mutex
+	//Обновления текущей пачки, ждущие Commit
+	inFlight   []webhookUpdate
+	lastPruned time.Time
+}
+
+type webhookUpdate struct {
+	update telegram.Update
+	done   chan error
+}
+
+var (
+	// ErrNoSecretToken без секрета любой, кто видит порт, сможет присылать поддельные обновления
+	ErrNoSecretToken = errors.New("webhook secret token is empty")
+	// ErrInvalidSecretToken такой секрет Telegram не примет в setWebhook
+	ErrInvalidSecretToken = errors.New("webhook secret token must be 1-256 characters A-Z, a-z, 0-9, _ or -")
+)
+
+// secretTokenPattern символы и длина, которые Telegram допускает в secret_token
+var secretTokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+func NewWebhookFetcher(secretToken string, updates storage.UpdateStore) (*WebhookFetcher, error) {
+	if secretToken == "" {
+		return nil, ErrNoSecretToken
+	}
+	if !secretTokenPattern.MatchString(secretToken) {
+		return nil, ErrInvalidSecretToken
+	}
+
+	return &WebhookFetcher{
+		secretToken: secretToken,
+		updates:     updates,
+		queue:       make(chan webhookUpdate, webhookQueueSize),
+	}, nil
+}
+
+func (f *WebhookFetcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	//Пустой секрет совпал бы с отсутствующим заголовком
+	token := r.Header.Get(secretTokenHeader)
+	if f.secretToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(f.secretToken)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var upd telegram.Update
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		log.Printf("[ERR] webhook: can't decode update: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	item := webhookUpdate{update: upd, done: make(chan error, 1)}
+
+	//Пока очередь занята, Telegram ждёт ответа и не присылает новые обновления
+	select {
+	case f.queue <- item:
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	//Если Telegram не дождётся ответа, он пришлёт обновление ещё раз, а повтор отбросит IsProcessed
+	select {
+	case err := <-item.done:
+		if err != nil {
+			log.Printf("[ERR] webhook: update %d is not processed: %s", upd.Id, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func (f *WebhookFetcher) Fetch(ctx context.Context, limit int, timeout int) (res []events.Event, err error) {
+	defer func() { err = e.WrapIfNil("can't get webhook events", err) }()
+
+	timer := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer timer.Stop()
+
+	var updates []webhookUpdate
+
+	//Ждём первое обновление не дольше timeout, остальные забираем без ожидания
+	select {
+	case upd := <-f.queue:
+		updates = append(updates, upd)
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+drain:
+	for len(updates) < limit {
+		select {
+		case upd := <-f.queue:
+			updates = append(updates, upd)
+		default:
+			break drain
+		}
+	}
+
+	res = make([]events.Event, 0, len(updates))
+
+	for _, u := range updates {
+		//Telegram повторяет доставку, если не получил ответ вовремя
+		processed, err := f.updates.IsProcessed(ctx, u.update.Id)
+		if err != nil {
+			//Telegram получит ошибку и доставит пачку заново
+			for _, u := range updates {
+				u.done <- err
+			}
+			return nil, err
+		}
+		if processed {
+			log.Printf("skip already processed update %d", u.update.Id)
+			continue
+		}
+
+		res = append(res, event(u.update))
+	}
+
+	f.mu.Lock()
+	f.inFlight = append(f.inFlight, updates...)
+	f.mu.Unlock()
+
+	return res, nil
+}
+
+func (f *WebhookFetcher) Ack(ctx context.Context, event events.Event) error {
+	meta, err := meta(event)
+	if err != nil {
+		return e.Wrap("can't ack event", err)
+	}
+
+	if err := f.updates.MarkProcessed(ctx, meta.UpdateId); err != nil {
+		return e.Wrap("can't ack event", err)
+	}
+
+	return nil
+}
+
+// Commit отвечает Telegram на обновления обработанной пачки и раз в pruneInterval удаляет старые отметки об обработке.
+// Offset при работе через webhook не используется
+func (f *WebhookFetcher) Commit(ctx context.Context) error {
+	f.mu.Lock()
+	inFlight := f.inFlight
+	f.inFlight = nil
+	prune := time.Since(f.lastPruned) >= pruneInterval
+	if prune {
+		f.lastPruned = time.Now()
+	}
+	f.mu.Unlock()
+
+	for _, u := range inFlight {
+		u.done <- nil
+	}
+
+	if !prune {
+		return nil
+	}
+
+	if err := f.updates.RemoveProcessedBefore(ctx, time.Now().Add(-processedTTL)); err != nil {
+		return e.Wrap("can't commit webhook events", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"tg_game_wishlist/events"
+	"tg_game_wishlist/storage/memory"
+	"time"
+)
+
+const testSecretToken = "test-secret"
+
+func postUpdate(t *testing.T, url string, secretToken string, name string) int {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("can't open recorded update: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	req, err := http.NewRequest(http.MethodPost, url, f)
+	if err != nil {
+		t.Fatalf("can't create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(secretTokenHeader, secretToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("can't post update: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+func newTestWebhookFetcher(t *testing.T) *WebhookFetcher {
+	t.Helper()
+
+	fetcher, err := NewWebhookFetcher(testSecretToken, memory.New())
+	if err != nil {
+		t.Fatalf("NewWebhookFetcher: %v", err)
+	}
+
+	return fetcher
+}
+
+// postQueued отправляет обновление и ждёт, пока оно попадёт в очередь; код ответа приходит только после Commit
+func postQueued(t *testing.T, fetcher *WebhookFetcher, url string, name string) <-chan int {
+	t.Helper()
+
+	queued := len(fetcher.queue)
+	codes := make(chan int, 1)
+	go func() {
+		codes <- postUpdate(t, url, testSecretToken, name)
+	}()
+
+	for len(fetcher.queue) == queued {
+		time.Sleep(time.Millisecond)
+	}
+
+	return codes
+}
+
+func TestWebhookFetcher(t *testing.T) {
+	ctx := context.Background()
+	fetcher := newTestWebhookFetcher(t)
+
+	server := httptest.NewServer(fetcher)
+	defer server.Close()
+
+	message := postQueued(t, fetcher, server.URL, "message.json")
+	callback := postQueued(t, fetcher, server.URL, "callback_query.json")
+
+	got, err := fetcher.Fetch(ctx, 10, 1)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Fetch returned %d events, want 2", len(got))
+	}
+
+	if got[0].Type != events.Message || got[0].Text != "Hollow Knight" {
+		t.Fatalf("first event = %+v, want message 'Hollow Knight'", got[0])
+	}
+	want := Meta{UpdateId: 512345001, ChatId: 123456789, UserId: 123456789, UserName: "ivan_gamer"}
+	if got[0].Meta != want {
+		t.Fatalf("first event meta = %+v, want %+v", got[0].Meta, want)
+	}
+
+	if got[1].Type != events.CallbackQuery || got[1].Id != "4382bfdwdsb323b2d9" || got[1].Text != "select:14593" {
+		t.Fatalf("second event = %+v, want callback 'select:14593'", got[1])
+	}
+
+	for _, event := range got {
+		if err := fetcher.Ack(ctx, event); err != nil {
+			t.Fatalf("Ack: %v", err)
+		}
+	}
+
+	//До конца обработки пачки Telegram ответа не получает
+	select {
+	case code := <-message:
+		t.Fatalf("POST message answered with %d before Commit", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := fetcher.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	for name, codes := range map[string]<-chan int{"message": message, "callback query": callback} {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("POST %s = %d, want 200", name, code)
+		}
+	}
+
+	//Повторная доставка того же обновления отбрасывается, но Telegram всё равно получает ответ
+	repeated := postQueued(t, fetcher, server.URL, "message.json")
+
+	got, err = fetcher.Fetch(ctx, 10, 1)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Fetch after redelivery returned %d events, want 0", len(got))
+	}
+
+	if err := fetcher.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if code := <-repeated; code != http.StatusOK {
+		t.Fatalf("POST repeated message = %d, want 200", code)
+	}
+}
+
+func TestWebhookFetcherSecretToken(t *testing.T) {
+	fetcher := newTestWebhookFetcher(t)
+
+	server := httptest.NewServer(fetcher)
+	defer server.Close()
+
+	for _, token := range []string{"", "wrong-secret"} {
+		if code := postUpdate(t, server.URL, token, "message.json"); code != http.StatusUnauthorized {
+			t.Fatalf("POST with token %q = %d, want 401", token, code)
+		}
+	}
+
+	got, err := fetcher.Fetch(context.Background(), 10, 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("Fetch returned %d events for rejected updates, want 0", len(got))
+	}
+}
+
+func TestWebhookFetcherEmptySecretToken(t *testing.T) {
+	if _, err := NewWebhookFetcher("", memory.New()); !errors.Is(err, ErrNoSecretToken) {
+		t.Fatalf("NewWebhookFetcher with empty secret = %v, want ErrNoSecretToken", err)
+	}
+
+	//Даже если fetcher создан в обход конструктора, запрос без заголовка не проходит
+	fetcher := &WebhookFetcher{updates: memory.New(), queue: make(chan webhookUpdate, 1)}
+
+	server := httptest.NewServer(fetcher)
+	defer server.Close()
+
+	if code := postUpdate(t, server.URL, "", "message.json"); code != http.StatusUnauthorized {
+		t.Fatalf("POST without token to server with empty secret = %d, want 401", code)
+	}
+}
+
+func TestWebhookFetcherInvalidSecretToken(t *testing.T) {
+	for _, token := range []string{"with space", "secret:token", "секрет", strings.Repeat("a", 257)} {
+		if _, err := NewWebhookFetcher(token, memory.New()); !errors.Is(err, ErrInvalidSecretToken) {
+			t.Errorf("NewWebhookFetcher(%q) = %v, want ErrInvalidSecretToken", token, err)
+		}
+	}
+
+	for _, token := range []string{"a", "Secret_token-42", strings.Repeat("a", 256)} {
+		if _, err := NewWebhookFetcher(token, memory.New()); err != nil {
+			t.Errorf("NewWebhookFetcher(%q) = %v, want no error", token, err)
+		}
+	}
+}
@@ -4,6 +4,10 @@ import "context"
 
 type Fetcher interface {
 	Fetch(ctx context.Context, limit int, timeout int) ([]Event, error)
+	// Ack отмечает событие обработанным, повторно оно не будет получено из Fetch
+	Ack(ctx context.Context, e Event) error
+	// Commit сохраняет прогресс после обработки всех событий последнего Fetch
+	Commit(ctx context.Context) error
 }
 
 type Processor interface {
@@ -16,6 +20,9 @@ const (
 	Unknown = iota
 	Message
 	CallbackQuery
+	InlineQuery
+	ChatMember
+	ChatMigration
 )
 
 type Event struct {
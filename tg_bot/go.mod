@@ -8,3 +8,7 @@ require (
 )
 
 require github.com/mattn/go-sqlite3 v1.14.33
+
+require github.com/lib/pq v1.10.9
+
+require golang.org/x/time v0.9.0
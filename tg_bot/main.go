@@ -2,13 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"tg_game_wishlist/api"
+	"tg_game_wishlist/api/cache"
 	"tg_game_wishlist/api/igdb"
+	"tg_game_wishlist/api/rawg"
 	tgClient "tg_game_wishlist/clients/telegram"
 	event_consumer "tg_game_wishlist/consumer/event-consumer"
+	"tg_game_wishlist/events"
 	"tg_game_wishlist/events/telegram"
 	tgNotifier "tg_game_wishlist/notifier/telegram"
+	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/memory"
+	"tg_game_wishlist/storage/postgres"
 	"tg_game_wishlist/storage/sqlite"
 	"time"
 
@@ -21,8 +32,25 @@ const (
 	timeout             = 60
 	httpTimeoutAddition = 5
 	igdbHost            = "api.igdb.com"
+	igdbTokenCachePath  = "igdb_token.json"
+	rawgHost            = "api.rawg.io"
+	//Кэш ответов IGDB: поиск меняется чаще, чем данные конкретной игры
+	finderCacheSize     = 1000
+	searchCacheTTL      = time.Hour
+	gameCacheTTL        = 24 * time.Hour
+	negativeCacheTTL    = 10 * time.Minute
+	cacheStatsInterval  = time.Hour
 	sqliteStoragePath   = "storage.db"
+	sqliteStorageType   = "sqlite"
+	postgresStorageType = "postgres"
+	memoryStorageType   = "memory"
+	pollingMode         = "polling"
+	webhookMode         = "webhook"
+	webhookListenAddr   = ":8080"
 	notifierDuration    = time.Second * 24
+	//Лимиты Telegram на отправку сообщений
+	tgMessagesPerSecond = 30
+	tgChatInterval      = time.Second
 )
 
 func init() {
@@ -33,34 +61,44 @@ func init() {
 }
 
 func main() {
-	s, err := sqlite.New(sqliteStoragePath)
-	if err != nil {
-		log.Fatal("can't connect to storage: ", err)
-	}
+	mode := flag.String("mode", pollingMode, "how to receive updates: polling or webhook")
+	flag.Parse()
 
-	if err := s.Init(context.TODO()); err != nil {
+	s, err := newStorage(context.TODO())
+	if err != nil {
 		log.Fatal("can't init storage: ", err)
 	}
 
 	token := mustEnv("TG_BOT_TOKEN")
-	apiClientId := mustEnv("API_CLIENT_ID")
-	apiToken := mustEnv("API_TOKEN")
-	apiTokenType := mustEnv("API_TOKEN_TYPE")
 
 	client := tgClient.New(tgBotHost, token, timeout+httpTimeoutAddition)
+	sendQueue := tgClient.NewQueue(client, tgMessagesPerSecond, tgChatInterval)
+
+	finder := newCachedFinder(newFinder(), s)
+	go logCacheStats(finder, cacheStatsInterval)
 
 	processor := telegram.NewProcessor(
-		client,
-		igdb.New(igdbHost, apiClientId, apiTokenType, apiToken),
+		sendQueue,
+		finder,
+		s,
+		s,
 		s,
 	)
 
-	fetcher := telegram.NewFetcher(client)
+	//Без меню бот работает, поэтому ошибка не останавливает запуск
+	if err := processor.RegisterCommands(context.TODO()); err != nil {
+		log.Print("[ERR] ", err)
+	}
+
+	fetcher, err := newFetcher(context.TODO(), *mode, client, s)
+	if err != nil {
+		log.Fatal("can't init fetcher: ", err)
+	}
 
 	consumer := event_consumer.New(fetcher, processor, batchSize, timeout)
 
 	//notifier
-	notifier := tgNotifier.New(s, client, notifierDuration)
+	notifier := tgNotifier.New(s, sendQueue, notifierDuration)
 	notifier.Start(context.Background())
 
 	log.Print("service started")
@@ -70,9 +108,131 @@ func main() {
 	}
 }
 
+// newFinder ищет игры в IGDB, а если доступа к IGDB нет (не задан API_CLIENT_ID) — в RAWG по RAWG_API_KEY.
+// В IGDB бот авторизуется по client secret с автоматическим обновлением токена, а без него — по заранее выданному API_TOKEN
+func newFinder() api.Finder {
+	clientId := os.Getenv("API_CLIENT_ID")
+	if clientId == "" {
+		key := os.Getenv("RAWG_API_KEY")
+		if key == "" {
+			log.Fatal("API_CLIENT_ID or RAWG_API_KEY is not specified")
+		}
+		log.Print("IGDB credentials are not specified, using RAWG")
+
+		return rawg.New(rawgHost, key)
+	}
+
+	if secret := os.Getenv("API_CLIENT_SECRET"); secret != "" {
+		endpoint := os.Getenv("API_OAUTH_URL")
+		if endpoint == "" {
+			endpoint = igdb.TwitchTokenURL
+		}
+
+		//Пустой IGDB_TOKEN_CACHE_PATH отключает сохранение токена в файл
+		cachePath, ok := os.LookupEnv("IGDB_TOKEN_CACHE_PATH")
+		if !ok {
+			cachePath = igdbTokenCachePath
+		}
+
+		return igdb.New(igdbHost, clientId, igdb.NewClientCredentials(endpoint, clientId, secret, cachePath))
+	}
+
+	return igdb.New(igdbHost, clientId, igdb.NewStaticToken(mustEnv("API_TOKEN_TYPE"), mustEnv("API_TOKEN")))
+}
+
+// newCachedFinder кэширует ответы finder в памяти, а если хранилище это умеет (sqlite, postgres), то и в нём
+func newCachedFinder(finder api.Finder, s botStorage) *cache.Finder {
+	store, _ := s.(storage.CacheStore)
+
+	return cache.New(finder, store, cache.Config{
+		Namespace:   fmt.Sprintf("%d:", finder.Source()),
+		Size:        finderCacheSize,
+		SearchTTL:   searchCacheTTL,
+		GameTTL:     gameCacheTTL,
+		NegativeTTL: negativeCacheTTL,
+	})
+}
+
+// logCacheStats раз в interval пишет в лог, сколько запросов к finder обошлось без обращения к API
+func logCacheStats(finder *cache.Finder, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		stats := finder.Stats()
+		log.Printf("finder cache: %d hits, %d misses", stats.Hits, stats.Misses)
+	}
+}
+
+// botStorage все данные бота (списки желаемого, состояния диалогов, ссылки на списки, прогресс обновлений) лежат в одном хранилище
+type botStorage interface {
+	storage.Storage
+	storage.StateStore
+	storage.UpdateStore
+	storage.ShareStore
+}
+
+// Хранилище выбирается переменной STORAGE_TYPE, по умолчанию используется sqlite
+func newStorage(ctx context.Context) (botStorage, error) {
+	switch storageType := os.Getenv("STORAGE_TYPE"); storageType {
+	case "", sqliteStorageType:
+		s, err := sqlite.New(sqliteStoragePath)
+		if err != nil {
+			return nil, err
+		}
+		return s, s.Init(ctx)
+	case postgresStorageType:
+		s, err := postgres.New(mustEnv("POSTGRES_DSN"))
+		if err != nil {
+			return nil, err
+		}
+		return s, s.Init(ctx)
+	case memoryStorageType:
+		return memory.New(), nil
+	default:
+		return nil, errors.New("unknown storage type: " + storageType)
+	}
+}
+
+// В режиме webhook обновления принимает HTTP-сервер, адрес для Telegram задаётся в WEBHOOK_URL
+func newFetcher(ctx context.Context, mode string, client *tgClient.Client, s storage.UpdateStore) (events.Fetcher, error) {
+	switch mode {
+	case pollingMode:
+		//getUpdates не работает, пока установлен webhook
+		if err := client.DeleteWebhook(ctx, false); err != nil {
+			return nil, err
+		}
+		return telegram.NewFetcher(client, s), nil
+	case webhookMode:
+		secretToken := mustEnv("WEBHOOK_SECRET")
+		fetcher, err := telegram.NewWebhookFetcher(secretToken, s)
+		if err != nil {
+			return nil, err
+		}
+
+		listenAddr := os.Getenv("WEBHOOK_LISTEN_ADDR")
+		if listenAddr == "" {
+			listenAddr = webhookListenAddr
+		}
+
+		go func() {
+			if err := http.ListenAndServe(listenAddr, fetcher); err != nil {
+				log.Fatal("webhook server is stopped: ", err)
+			}
+		}()
+
+		if err := client.SetWebhook(ctx, mustEnv("WEBHOOK_URL"), secretToken); err != nil {
+			return nil, err
+		}
+		return fetcher, nil
+	default:
+		return nil, errors.New("unknown mode: " + mode)
+	}
+}
+
 func mustEnv(envName string) string {
 	env := os.Getenv(envName)
-	if envName == "" {
+	if env == "" {
 		log.Fatal(envName + " is not specified")
 	}
 	return env
@@ -2,6 +2,7 @@ package telegram
 
 import (
 	"context"
+	"errors"
 	"log"
 	"strings"
 	"tg_game_wishlist/clients/telegram"
@@ -12,15 +13,22 @@ import (
 
 type Notifier struct {
 	storage  storage.Storage
-	tg       *telegram.Client
+	tg       *telegram.Queue
 	interval time.Duration
+	//unreachable чаты, в которые не удалось доставить уведомления, и время следующей попытки.
+	//Уведомления остаются неотправленными: пользователь может разблокировать бота
+	unreachable map[int]time.Time
 }
 
-func New(storage storage.Storage, tg *telegram.Client, duration time.Duration) *Notifier {
+// unreachableRetry как часто пробовать доставить уведомления в недоступный чат
+const unreachableRetry = 6 * time.Hour
+
+func New(storage storage.Storage, tg *telegram.Queue, duration time.Duration) *Notifier {
 	return &Notifier{
-		storage:  storage,
-		tg:       tg,
-		interval: duration,
+		storage:     storage,
+		tg:          tg,
+		interval:    duration,
+		unreachable: make(map[int]time.Time),
 	}
 }
 
@@ -34,7 +42,7 @@ func (n *Notifier) Start(ctx context.Context) error {
 			case <-ticker.C:
 				wishlist, err := n.storage.GetToNotify(ctx)
 				if err != nil {
-					log.Printf("[ERR] can't get wishlists to notify: %w", err)
+					log.Printf("[ERR] can't get wishlists to notify: %s", err)
 					continue
 				}
 
@@ -49,32 +57,12 @@ func (n *Notifier) Start(ctx context.Context) error {
 				}
 
 				for chatId, uw := range userWishlist {
-					var builder strings.Builder
-					builder.WriteString(notifier.MsgTodayGameReleases)
-
-					for _, w := range uw {
-						builder.WriteString("\n\n")
-						builder.WriteString("🔥 ")
-						builder.WriteString(w.Game.Name)
-
-						if w.Game.ExternalURL != "" {
-							builder.WriteString("\n🌐 ")
-							builder.WriteString(w.Game.ExternalURL)
-						}
-					}
-
-					err = n.tg.SendMessage(ctx, chatId, builder.String())
-					if err != nil {
-						log.Printf("[ERR] can't send notification: %w", err)
+					if retryAt, ok := n.unreachable[chatId]; ok && time.Now().Before(retryAt) {
 						continue
 					}
+					delete(n.unreachable, chatId)
 
-					for _, w := range uw {
-						err = n.storage.Notify(ctx, &w)
-						if err != nil {
-							log.Printf("[ERR] can't storage notify: %w", err)
-						}
-					}
+					n.notifyChat(ctx, chatId, uw)
 				}
 
 			case <-ctx.Done():
@@ -87,3 +75,126 @@ func (n *Notifier) Start(ctx context.Context) error {
 
 	return nil
 }
+
+// notifyChat отправляет уведомления одного чата. Уведомлёнными отмечаются только записи из доставленных сообщений,
+// поэтому после сбоя на следующем тике отправляются только недошедшие
+func (n *Notifier) notifyChat(ctx context.Context, chatId int, uw []storage.Wishlist) {
+	header := notifier.MsgTodayGameReleases
+
+	for _, batch := range batches(header, uw) {
+		err := n.send(ctx, chatId, header, batch)
+		header = ""
+
+		if err == nil {
+			n.notify(ctx, batch)
+			continue
+		}
+
+		if errors.Is(err, telegram.ErrBadRequest) {
+			//Telegram отверг разметку: то же без неё
+			log.Printf("[ERR] can't send notification, retrying without markup: %s", err)
+			if err = n.sendPlain(ctx, chatId, batch); err == nil {
+				continue
+			}
+		}
+
+		//Недоступный чат или отвергнутое сообщение давали бы ту же ошибку на каждом тике
+		if errors.Is(err, telegram.ErrBotBlocked) || errors.Is(err, telegram.ErrChatNotFound) || errors.Is(err, telegram.ErrBadRequest) {
+			log.Printf("[ERR] can't send notification to chat %d, next try in %s: %s", chatId, unreachableRetry, err)
+			n.unreachable[chatId] = time.Now().Add(unreachableRetry)
+			return
+		}
+
+		log.Printf("[ERR] can't send notification: %s", err)
+		return
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, uw []storage.Wishlist) {
+	for _, w := range uw {
+		if err := n.storage.Notify(ctx, &w); err != nil {
+			log.Printf("[ERR] can't storage notify: %s", err)
+		}
+	}
+}
+
+// batches делит записи на группы, каждая из которых помещается в одно сообщение; заголовок только у первой.
+// Запись длиннее сообщения образует отдельную группу и отправляется несколькими сообщениями
+func batches(header string, uw []storage.Wishlist) [][]storage.Wishlist {
+	var res [][]storage.Wishlist
+	var batch []storage.Wishlist
+	var entries []string
+
+	for _, w := range uw {
+		e := entry(w)
+		if len(batch) > 0 && len(telegram.SplitMessage(header, append(entries, e))) > 1 {
+			res = append(res, batch)
+			batch, entries, header = nil, nil, ""
+		}
+
+		batch = append(batch, w)
+		entries = append(entries, e)
+	}
+	if len(batch) > 0 {
+		res = append(res, batch)
+	}
+
+	return res
+}
+
+func entry(w storage.Wishlist) string {
+	return formatEntry(w, telegram.Link(w.Game.Name, w.Game.ExternalURL), telegram.EscapeHTML)
+}
+
+func plainEntry(w storage.Wishlist) string {
+	name := w.Game.Name
+	if w.Game.ExternalURL != "" {
+		name += " " + w.Game.ExternalURL
+	}
+
+	return telegram.Truncate(formatEntry(w, name, func(s string) string { return s }), telegram.MaxMessageLength)
+}
+
+func formatEntry(w storage.Wishlist, name string, escape func(string) string) string {
+	var builder strings.Builder
+	builder.WriteString("🔥 ")
+	builder.WriteString(name)
+
+	if len(w.Platforms) > 0 {
+		names := make([]string, 0, len(w.Platforms))
+		for _, platform := range w.Platforms {
+			names = append(names, platform.Name)
+		}
+		builder.WriteString("\n🕹️ ")
+		builder.WriteString(escape(strings.Join(names, " | ")))
+	}
+
+	return builder.String()
+}
+
+func (n *Notifier) send(ctx context.Context, chatId int, header string, batch []storage.Wishlist) error {
+	entries := make([]string, 0, len(batch))
+	for _, w := range batch {
+		entries = append(entries, entry(w))
+	}
+
+	for _, text := range telegram.SplitMessage(header, entries) {
+		if err := n.tg.SendMessage(ctx, chatId, text, telegram.ParseModeHTML); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendPlain отправляет записи без заголовка и разметки, по сообщению на запись, и сразу отмечает доставленные
+func (n *Notifier) sendPlain(ctx context.Context, chatId int, batch []storage.Wishlist) error {
+	for i, w := range batch {
+		if err := n.tg.SendMessage(ctx, chatId, plainEntry(w), telegram.PlainText); err != nil {
+			return err
+		}
+		n.notify(ctx, batch[i:i+1])
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"tg_game_wishlist/clients/telegram"
+	"tg_game_wishlist/notifier"
+	"tg_game_wishlist/storage"
+)
+
+func wishlist(id int, nameLength int) storage.Wishlist {
+	return storage.Wishlist{
+		Id:   id,
+		Game: &storage.Game{Name: strings.Repeat("a", nameLength), ExternalURL: "https://www.igdb.com/games/a"},
+	}
+}
+
+func TestBatches(t *testing.T) {
+	//В сообщение помещаются две записи такой длины
+	uw := []storage.Wishlist{
+		wishlist(1, 1800),
+		wishlist(2, 1800),
+		wishlist(3, 1800),
+		wishlist(4, 5000),
+		wishlist(5, 1800),
+	}
+
+	res := batches(notifier.MsgTodayGameReleases, uw)
+
+	var ids [][]int
+	for _, batch := range res {
+		var batchIds []int
+		for _, w := range batch {
+			batchIds = append(batchIds, w.Id)
+		}
+		ids = append(ids, batchIds)
+	}
+
+	//Запись длиннее сообщения отправляется отдельно
+	want := [][]int{{1, 2}, {3}, {4}, {5}}
+	if !slices.EqualFunc(ids, want, slices.Equal) {
+		t.Fatalf("batches = %v, want %v", ids, want)
+	}
+
+	//Каждая группа, кроме слишком длинной записи, уходит одним сообщением
+	for i, batch := range res {
+		if i == 2 {
+			continue
+		}
+		entries := make([]string, 0, len(batch))
+		for _, w := range batch {
+			entries = append(entries, entry(w))
+		}
+		if messages := telegram.SplitMessage("", entries); len(messages) != 1 {
+			t.Fatalf("batch %v is split into %d messages", ids[i], len(messages))
+		}
+	}
+}
+
+func TestPlainEntry(t *testing.T) {
+	w := storage.Wishlist{
+		Game:      &storage.Game{Name: "Ori & <the> Blind Forest", ExternalURL: "https://www.igdb.com/games/ori"},
+		Platforms: []storage.Platform{{Name: "PC"}, {Name: "Xbox <One>"}},
+	}
+
+	want := "🔥 Ori & <the> Blind Forest https://www.igdb.com/games/ori\n🕹️ PC | Xbox <One>"
+	if got := plainEntry(w); got != want {
+		t.Fatalf("plainEntry = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,477 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+var ErrAlreadyExists = errors.New("wishlist already exists")
+
+// Storage хранит данные в памяти процесса, подходит для тестов и локального запуска
+type Storage struct {
+	mu       sync.Mutex
+	users    map[int]*storage.User
+	games    map[int]*storage.Game
+	wishlist map[int]*storage.Wishlist
+	states   map[int]storage.State
+	shares   map[string]storage.Share
+	offset   int
+	updates  map[int]time.Time
+	lastId   int
+}
+
+func New() *Storage {
+	return &Storage{
+		users:    make(map[int]*storage.User),
+		games:    make(map[int]*storage.Game),
+		wishlist: make(map[int]*storage.Wishlist),
+		states:   make(map[int]storage.State),
+		shares:   make(map[string]storage.Share),
+		updates:  make(map[int]time.Time),
+	}
+}
+
+func (s *Storage) Add(ctx context.Context, w *storage.Wishlist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//Получение или создание пользователя
+	u := s.getOrCreateUser(w.User)
+	w.User.Id = u.Id
+
+	//Получение или создание игры
+	g := s.getOrCreateGame(w.Game)
+	w.Game.Id = g.Id
+
+	if s.find(u.Id, g.Id) != nil {
+		return ErrAlreadyExists
+	}
+
+	//Участник отмечается только в чужом (групповом) списке
+	var addedBy *storage.User
+	if w.AddedBy != nil && w.AddedBy.TelegramId != u.TelegramId {
+		addedBy = s.getOrCreateUser(w.AddedBy)
+		w.AddedBy.Id = addedBy.Id
+	}
+
+	w.Id = s.nextId()
+	s.wishlist[w.Id] = &storage.Wishlist{
+		Id:               w.Id,
+		User:             u,
+		AddedBy:          addedBy,
+		Game:             g,
+		NotificationDate: w.NotificationDate,
+		AddedAt:          time.Now(),
+		Platforms:        append([]storage.Platform(nil), w.Platforms...),
+	}
+
+	return nil
+}
+
+func (s *Storage) IsExists(ctx context.Context, w *storage.Wishlist) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.userByTelegramId(w.User.TelegramId)
+	if u == nil {
+		return false, nil
+	}
+
+	g := s.game(w.Game)
+	if g == nil {
+		return false, nil
+	}
+
+	return s.find(u.Id, g.Id) != nil, nil
+}
+
+func (s *Storage) GetUserByTelegramId(ctx context.Context, telegramId int) (*storage.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.userByTelegramId(telegramId)
+	if u == nil {
+		return nil, storage.ErrNoUser
+	}
+
+	res := *u
+	return &res, nil
+}
+
+func (s *Storage) Get(ctx context.Context, wishlistId int) (*storage.Wishlist, error) {
+	res := s.filter(func(w *storage.Wishlist) bool {
+		return w.Id == wishlistId
+	})
+	if len(res) == 0 {
+		return nil, storage.ErrNoWishlist
+	}
+
+	return &res[0], nil
+}
+
+func (s *Storage) GetAll(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	return s.filter(func(w *storage.Wishlist) bool {
+		return w.User.Id == u.Id
+	}), nil
+}
+
+func (s *Storage) GetPage(ctx context.Context, u *storage.User, limit int, offset int) ([]storage.Wishlist, error) {
+	all, err := s.GetAll(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(all) {
+		return nil, nil
+	}
+
+	return all[offset:min(offset+limit, len(all))], nil
+}
+
+func (s *Storage) Count(ctx context.Context, u *storage.User) (int, error) {
+	all, err := s.GetAll(ctx, u)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(all), nil
+}
+
+func (s *Storage) GetReleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	today := day(time.Now())
+
+	return s.filter(func(w *storage.Wishlist) bool {
+		return w.User.Id == u.Id && !w.Game.ReleaseDate.IsZero() && !day(w.Game.ReleaseDate).After(today)
+	}), nil
+}
+
+func (s *Storage) GetUnreleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	today := day(time.Now())
+
+	res := s.filter(func(w *storage.Wishlist) bool {
+		return w.User.Id == u.Id && (w.Game.ReleaseDate.IsZero() || day(w.Game.ReleaseDate).After(today))
+	})
+
+	//Сначала ближайшие релизы, игры без даты в конце
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i].Game.ReleaseDate, res[j].Game.ReleaseDate
+		if a.IsZero() || b.IsZero() {
+			return !a.IsZero() && b.IsZero()
+		}
+		return a.Before(b)
+	})
+
+	return res, nil
+}
+
+func (s *Storage) Remove(ctx context.Context, wishlistId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.wishlist, wishlistId)
+
+	return nil
+}
+
+func (s *Storage) GetToNotify(ctx context.Context) ([]storage.Wishlist, error) {
+	today := day(time.Now())
+
+	return s.filter(func(w *storage.Wishlist) bool {
+		return w.NotifiedAt.IsZero() && !w.NotificationDate.IsZero() && !day(w.NotificationDate).After(today)
+	}), nil
+}
+
+func (s *Storage) Notify(ctx context.Context, w *storage.Wishlist) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.wishlist[w.Id]
+	if ok && stored.NotifiedAt.IsZero() {
+		stored.NotifiedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (s *Storage) MigrateChat(ctx context.Context, oldChatId int, newChatId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	//Ссылки на список переходят к супергруппе вместе с ним
+	for token, share := range s.shares {
+		if share.TelegramId == oldChatId {
+			share.TelegramId = newChatId
+			s.shares[token] = share
+		}
+	}
+
+	old := s.userByTelegramId(oldChatId)
+	if old == nil {
+		return nil
+	}
+
+	current := s.userByTelegramId(newChatId)
+	if current == nil {
+		old.TelegramId = newChatId
+		old.ChatId = newChatId
+		return nil
+	}
+
+	//Игры, которые уже есть в списке супергруппы, удаляются вместе со старой группой
+	for id, w := range s.wishlist {
+		if w.User.Id != old.Id {
+			continue
+		}
+		if s.find(current.Id, w.Game.Id) == nil {
+			w.User = current
+		} else {
+			delete(s.wishlist, id)
+		}
+	}
+	delete(s.users, old.Id)
+
+	return nil
+}
+
+func (s *Storage) GetState(ctx context.Context, telegramId int) (*storage.State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[telegramId]
+	if !ok {
+		return nil, storage.ErrNoState
+	}
+
+	return &state, nil
+}
+
+func (s *Storage) SaveState(ctx context.Context, state *storage.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states[state.TelegramId] = *state
+
+	return nil
+}
+
+func (s *Storage) RemoveState(ctx context.Context, telegramId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.states, telegramId)
+
+	return nil
+}
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shares[share.Token] = *share
+
+	return nil
+}
+
+func (s *Storage) GetShare(ctx context.Context, token string) (*storage.Share, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	share, ok := s.shares[token]
+	if !ok {
+		return nil, storage.ErrNoShare
+	}
+
+	return &share, nil
+}
+
+func (s *Storage) RemoveShares(ctx context.Context, telegramId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for token, share := range s.shares {
+		if share.TelegramId == telegramId {
+			delete(s.shares, token)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) GetOffset(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.offset, nil
+}
+
+func (s *Storage) SaveOffset(ctx context.Context, offset int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset = offset
+
+	//Обновления до offset Telegram больше не пришлёт, их можно не хранить
+	for updateId := range s.updates {
+		if updateId < offset {
+			delete(s.updates, updateId)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) IsProcessed(ctx context.Context, updateId int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.updates[updateId]
+
+	return ok, nil
+}
+
+func (s *Storage) MarkProcessed(ctx context.Context, updateId int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.updates[updateId]; !ok {
+		s.updates[updateId] = time.Now()
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveProcessedBefore(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for updateId, processedAt := range s.updates {
+		if processedAt.Before(before) {
+			delete(s.updates, updateId)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) nextId() int {
+	s.lastId++
+	return s.lastId
+}
+
+func (s *Storage) userByTelegramId(telegramId int) *storage.User {
+	for _, u := range s.users {
+		if u.TelegramId == telegramId {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) getOrCreateUser(u *storage.User) *storage.User {
+	if stored := s.userByTelegramId(u.TelegramId); stored != nil {
+		//username и чат могут меняться, пользователь определяется только по telegram_id
+		stored.Name = u.Name
+		stored.ChatId = u.ChatId
+		return stored
+	}
+
+	stored := &storage.User{
+		Id:         s.nextId(),
+		TelegramId: u.TelegramId,
+		Name:       u.Name,
+		ChatId:     u.ChatId,
+	}
+	s.users[stored.Id] = stored
+
+	return stored
+}
+
+func (s *Storage) game(g *storage.Game) *storage.Game {
+	for _, stored := range s.games {
+		if stored.Name == g.Name && stored.ExternalURL == g.ExternalURL && stored.Source == g.Source {
+			return stored
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) getOrCreateGame(g *storage.Game) *storage.Game {
+	if stored := s.game(g); stored != nil {
+		//Дата выхода уже сохранённой игры не перезаписывается
+		if stored.ReleaseDate.IsZero() {
+			stored.ReleaseDate = g.ReleaseDate
+		}
+		if stored.ExternalId == 0 {
+			stored.ExternalId = g.ExternalId
+		}
+		return stored
+	}
+
+	stored := &storage.Game{
+		Id:          s.nextId(),
+		Name:        g.Name,
+		Source:      g.Source,
+		ExternalURL: g.ExternalURL,
+		ExternalId:  g.ExternalId,
+		ReleaseDate: g.ReleaseDate,
+	}
+	s.games[stored.Id] = stored
+
+	return stored
+}
+
+func (s *Storage) find(userId int, gameId int) *storage.Wishlist {
+	for _, w := range s.wishlist {
+		if w.User.Id == userId && w.Game.Id == gameId {
+			return w
+		}
+	}
+
+	return nil
+}
+
+// filter возвращает копии подходящих записей, отсортированные по названию игры
+func (s *Storage) filter(match func(w *storage.Wishlist) bool) []storage.Wishlist {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []storage.Wishlist
+
+	for _, w := range s.wishlist {
+		if !match(w) {
+			continue
+		}
+
+		u := *w.User
+		g := *w.Game
+		item := *w
+		item.User = &u
+		item.Game = &g
+		if w.AddedBy != nil {
+			a := *w.AddedBy
+			item.AddedBy = &a
+		}
+		item.Platforms = append([]storage.Platform(nil), w.Platforms...)
+
+		res = append(res, item)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Game.Name != res[j].Game.Name {
+			return res[i].Game.Name < res[j].Game.Name
+		}
+		return res[i].Id < res[j].Id
+	})
+
+	return res
+}
+
+// day отбрасывает время, сравнение дат идёт по UTC, как в sqlite
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"testing"
+	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return New()
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	storagetest.RunStateStore(t, func(t *testing.T) storage.StateStore {
+		return New()
+	})
+}
+
+func TestShareStore(t *testing.T) {
+	storagetest.RunShareStore(t, func(t *testing.T) storage.ShareStore {
+		return New()
+	})
+}
+
+func TestUpdateStore(t *testing.T) {
+	storagetest.RunUpdateStore(t, func(t *testing.T) storage.UpdateStore {
+		return New()
+	})
+}
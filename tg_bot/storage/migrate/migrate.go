@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"tg_game_wishlist/lib/e"
+)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+}
+
+var (
+	ErrSchemaTooNew    = errors.New("database schema is newer than the application knows")
+	ErrInvalidVersions = errors.New("migrations must be numbered sequentially from 1")
+)
+
+const createVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+`
+
+// Up применяет к базе все миграции, версия которых больше текущей.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью версии.
+func Up(ctx context.Context, db *sql.DB, migrations []Migration) (err error) {
+	defer func() { err = e.WrapIfNil("can't migrate database", err) }()
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return ErrInvalidVersions
+		}
+	}
+
+	if _, err := db.ExecContext(ctx, createVersionTable); err != nil {
+		return e.Wrap("can't create schema version table", err)
+	}
+
+	current, err := Version(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if current > len(migrations) {
+		return fmt.Errorf("%w: database version %d, latest known %d", ErrSchemaTooNew, current, len(migrations))
+	}
+
+	for _, m := range migrations[current:] {
+		if err := apply(ctx, db, m); err != nil {
+			return err
+		}
+		log.Printf("applied migration %d (%s)", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+// Version возвращает номер последней применённой миграции, 0 для пустой базы.
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	q := `SELECT COALESCE(MAX(version), 0) FROM schema_version`
+
+	var version int
+	if err := db.QueryRowContext(ctx, q).Scan(&version); err != nil {
+		return 0, e.Wrap("can't get schema version", err)
+	}
+
+	return version, nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration) (err error) {
+	defer func() { err = e.WrapIfNil(fmt.Sprintf("can't apply migration %d (%s)", m.Version, m.Name), err) }()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, m.Up); err != nil {
+		return err
+	}
+
+	// Версия подставляется напрямую, чтобы не зависеть от синтаксиса плейсхолдеров конкретной СУБД
+	q := fmt.Sprintf(`INSERT INTO schema_version (version, name) VALUES (%d, '%s')`, m.Version, m.Name)
+	if _, err := tx.ExecContext(ctx, q); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
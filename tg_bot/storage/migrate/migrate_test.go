@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "first", Up: `CREATE TABLE first (id INTEGER PRIMARY KEY);`},
+	{Version: 2, Name: "second", Up: `CREATE TABLE second (id INTEGER PRIMARY KEY);`},
+}
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatalf("can't open database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func mustVersion(t *testing.T, db *sql.DB, want int) {
+	t.Helper()
+
+	version, err := Version(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Version: %v", err)
+	}
+	if version != want {
+		t.Fatalf("Version = %d, want %d", version, want)
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+
+	var count int
+	q := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if err := db.QueryRow(q, name).Scan(&count); err != nil {
+		t.Fatalf("can't check table %s: %v", name, err)
+	}
+
+	return count == 1
+}
+
+func TestUp(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	if err := Up(ctx, db, testMigrations[:1]); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	mustVersion(t, db, 1)
+
+	//Повторный запуск применяет только новые миграции
+	for range 2 {
+		if err := Up(ctx, db, testMigrations); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+	}
+	mustVersion(t, db, 2)
+
+	if !tableExists(t, db, "first") || !tableExists(t, db, "second") {
+		t.Fatal("tables of applied migrations don't exist")
+	}
+}
+
+func TestUpInvalidVersions(t *testing.T) {
+	db := newTestDB(t)
+
+	for name, migrations := range map[string][]Migration{
+		"gap":         {testMigrations[0], {Version: 3, Name: "third", Up: `SELECT 1;`}},
+		"not from 1":  {testMigrations[1]},
+		"wrong order": {testMigrations[1], testMigrations[0]},
+	} {
+		if err := Up(context.Background(), db, migrations); !errors.Is(err, ErrInvalidVersions) {
+			t.Errorf("Up(%s) = %v, want ErrInvalidVersions", name, err)
+		}
+	}
+
+	if tableExists(t, db, "first") {
+		t.Fatal("migrations were applied despite invalid versions")
+	}
+}
+
+func TestUpSchemaTooNew(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	if err := Up(ctx, db, testMigrations); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	//Старая версия приложения не должна трогать базу, обновлённую новой
+	if err := Up(ctx, db, testMigrations[:1]); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("Up with older migrations = %v, want ErrSchemaTooNew", err)
+	}
+	mustVersion(t, db, 2)
+}
+
+func TestUpRollback(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+
+	migrations := []Migration{
+		testMigrations[0],
+		{Version: 2, Name: "broken", Up: `
+			CREATE TABLE second (id INTEGER PRIMARY KEY);
+			INSERT INTO missing (id) VALUES (1);
+		`},
+	}
+
+	if err := Up(ctx, db, migrations); err == nil {
+		t.Fatal("Up with broken migration: want error")
+	}
+
+	//Предыдущие миграции остаются, от сломанной не остаётся ни изменений, ни записи версии
+	mustVersion(t, db, 1)
+	if !tableExists(t, db, "first") {
+		t.Fatal("table of the first migration doesn't exist")
+	}
+	if tableExists(t, db, "second") {
+		t.Fatal("changes of the broken migration weren't rolled back")
+	}
+
+	//После исправления миграция применяется заново
+	if err := Up(ctx, db, testMigrations); err != nil {
+		t.Fatalf("Up after fix: %v", err)
+	}
+	mustVersion(t, db, 2)
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import "tg_game_wishlist/storage/migrate"
+
+// Новые изменения схемы добавляются только в конец списка, уже выпущенные миграции не редактируются
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "init",
+		Up: `
+			CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				telegram_id BIGINT NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				chat_id BIGINT NOT NULL,
+
+				UNIQUE(telegram_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS game (
+				id SERIAL PRIMARY KEY,
+				external_url VARCHAR(500) NULL,
+				source INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				release_date TIMESTAMPTZ NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+
+				UNIQUE(source, external_url, name)
+			);
+
+			CREATE TABLE IF NOT EXISTS wishlist (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				game_id INTEGER NOT NULL REFERENCES game(id) ON DELETE CASCADE,
+				notification_date TIMESTAMPTZ NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				notified_at TIMESTAMPTZ NULL,
+
+				UNIQUE(user_id, game_id)
+			);
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "wishlist_platform",
+		Up: `
+			CREATE TABLE wishlist_platform (
+				id SERIAL PRIMARY KEY,
+				wishlist_id INTEGER NOT NULL REFERENCES wishlist(id) ON DELETE CASCADE,
+				platform_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				release_date TIMESTAMPTZ NULL,
+
+				UNIQUE(wishlist_id, platform_id)
+			);
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "user_state",
+		Up: `
+			CREATE TABLE user_state (
+				telegram_id BIGINT PRIMARY KEY,
+				step VARCHAR(255) NOT NULL,
+				game_name VARCHAR(255) NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "telegram_updates",
+		Up: `
+			CREATE TABLE update_offset (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				value BIGINT NOT NULL
+			);
+
+			CREATE TABLE processed_update (
+				update_id BIGINT PRIMARY KEY,
+				processed_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "game_external_id",
+		Up: `
+			ALTER TABLE game ADD COLUMN external_id BIGINT NULL;
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "wishlist_added_by",
+		Up: `
+			ALTER TABLE wishlist ADD COLUMN added_by INTEGER NULL REFERENCES users(id) ON DELETE SET NULL;
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "wishlist_share",
+		Up: `
+			CREATE TABLE wishlist_share (
+				token VARCHAR(64) PRIMARY KEY,
+				telegram_id BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL
+			);
+
+			CREATE INDEX wishlist_share_telegram_id ON wishlist_share (telegram_id);
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "api_cache",
+		Up: `
+			CREATE TABLE api_cache (
+				key VARCHAR(255) PRIMARY KEY,
+				value BYTEA NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+	},
+}
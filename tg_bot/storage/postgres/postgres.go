@@ -0,0 +1,837 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"tg_game_wishlist/lib/e"
+	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/migrate"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Storage struct {
+	db *sql.DB
+}
+
+func (s *Storage) IsExists(ctx context.Context, w *storage.Wishlist) (res bool, err error) {
+	defer func() { err = e.WrapIfNil("can't check if exists wishlist", err) }()
+
+	userId, err := s.userId(ctx, w.User.TelegramId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	gameId, err := s.gameId(ctx, w.Game)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	q := `
+		SELECT COUNT(*) 
+		FROM wishlist 
+		WHERE user_id = $1 AND game_id = $2
+	`
+
+	var count int
+
+	err = s.db.QueryRowContext(ctx, q, userId, gameId).Scan(&count)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s *Storage) GetUserByTelegramId(ctx context.Context, telegramId int) (*storage.User, error) {
+	q := `
+		SELECT id, telegram_id, name, chat_id
+		FROM users 
+		WHERE telegram_id = $1
+	`
+
+	var u storage.User
+
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&u.Id, &u.TelegramId, &u.Name, &u.ChatId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoUser
+		}
+		return nil, err
+	}
+
+	return &u, nil
+}
+
+func (s *Storage) Add(ctx context.Context, w *storage.Wishlist) (err error) {
+	defer func() { err = e.WrapIfNil("can't add wishlist", err) }()
+	//Получение или создание пользователя
+	userId, err := s.getOrCreateUser(ctx, w.User)
+	if err != nil {
+		return err
+	}
+	w.User.Id = userId
+
+	//Получение или создание игры
+	gameId, err := s.getOrCreateGame(ctx, w.Game)
+	if err != nil {
+		return err
+	}
+	w.Game.Id = gameId
+
+	addedBy, err := s.addedBy(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO wishlist (game_id, user_id, added_by, notification_date) VALUES ($1, $2, $3, $4) RETURNING id`
+
+	//Запись и её платформы добавляются вместе, чтобы не остаться без платформ
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var wishlistId int
+
+	err = tx.QueryRowContext(ctx, q, gameId, userId, addedBy, nullTime(w.NotificationDate)).Scan(&wishlistId)
+	if err != nil {
+		return err
+	}
+
+	if err := addPlatforms(ctx, tx, wishlistId, w.Platforms); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	w.Id = wishlistId
+
+	return nil
+}
+
+// addedBy id участника, добавившего игру в список группы; в своём списке пользователь не отмечается
+func (s *Storage) addedBy(ctx context.Context, w *storage.Wishlist) (sql.NullInt64, error) {
+	if w.AddedBy == nil || w.AddedBy.TelegramId == w.User.TelegramId {
+		return sql.NullInt64{}, nil
+	}
+
+	userId, err := s.getOrCreateUser(ctx, w.AddedBy)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	w.AddedBy.Id = userId
+
+	return sql.NullInt64{Int64: int64(userId), Valid: true}, nil
+}
+
+// MigrateChat переносит список желаемого группы в супергруппу, в которую она превратилась.
+// Если в супергруппе уже успели что-то добавить, списки объединяются.
+func (s *Storage) MigrateChat(ctx context.Context, oldChatId int, newChatId int) (err error) {
+	defer func() { err = e.WrapIfNil("can't migrate chat", err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	//Ссылки на список переходят к супергруппе вместе с ним
+	q := `UPDATE wishlist_share SET telegram_id = $1 WHERE telegram_id = $2`
+	if _, err := tx.ExecContext(ctx, q, newChatId, oldChatId); err != nil {
+		return err
+	}
+
+	var oldId int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE telegram_id = $1`, oldChatId).Scan(&oldId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	var newId int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE telegram_id = $1`, newChatId).Scan(&newId)
+	if errors.Is(err, sql.ErrNoRows) {
+		q = `UPDATE users SET telegram_id = $1, chat_id = $2 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, q, newChatId, newChatId, oldId); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	q = `UPDATE wishlist SET user_id = $1 WHERE user_id = $2 AND game_id NOT IN (SELECT game_id FROM wishlist WHERE user_id = $3)`
+	if _, err := tx.ExecContext(ctx, q, newId, oldId, newId); err != nil {
+		return err
+	}
+
+	//Игры, которые уже есть в списке супергруппы, удаляются вместе со старой группой
+	q = `DELETE FROM wishlist_platform WHERE wishlist_id IN (SELECT id FROM wishlist WHERE user_id = $1)`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+	q = `DELETE FROM wishlist WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+	q = `DELETE FROM users WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) userId(ctx context.Context, telegramId int) (int, error) {
+	q := `SELECT id FROM users WHERE telegram_id = $1`
+
+	var id int
+
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, err
+		}
+		return -1, e.Wrap("can't check if user exists", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) addUser(ctx context.Context, u *storage.User) (int, error) {
+	q := `INSERT INTO users (telegram_id, name, chat_id) VALUES ($1, $2, $3) RETURNING id`
+
+	var userId int
+	if err := s.db.QueryRowContext(ctx, q, u.TelegramId, u.Name, u.ChatId).Scan(&userId); err != nil {
+		return -1, e.Wrap("can't create user", err)
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) refreshUser(ctx context.Context, userId int, u *storage.User) error {
+	//username и чат могут меняться, пользователь определяется только по telegram_id
+	q := `UPDATE users SET name = $1, chat_id = $2 WHERE id = $3 AND (name <> $1 OR chat_id <> $2)`
+
+	if _, err := s.db.ExecContext(ctx, q, u.Name, u.ChatId, userId); err != nil {
+		return e.Wrap("can't refresh user", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) getOrCreateUser(ctx context.Context, u *storage.User) (int, error) {
+	userId, err := s.userId(ctx, u.TelegramId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return -1, err
+	}
+
+	if userId < 0 {
+		return s.addUser(ctx, u)
+	}
+
+	if err := s.refreshUser(ctx, userId, u); err != nil {
+		return -1, err
+	}
+
+	return userId, nil
+}
+
+func (s *Storage) gameId(ctx context.Context, g *storage.Game) (int, error) {
+	q := `SELECT id 
+		  FROM game 
+		  WHERE name = $1 AND external_url = $2 AND source = $3`
+
+	var id int
+	err := s.db.QueryRowContext(ctx, q, g.Name, g.ExternalURL, g.Source).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, err
+		}
+		return -1, e.Wrap("can't check if game exists", err)
+	}
+
+	return id, nil
+}
+
+func (s *Storage) addGame(ctx context.Context, g *storage.Game) (int, error) {
+	q := `INSERT INTO game (name, source, external_url, external_id, release_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	var gameId int
+	if err := s.db.QueryRowContext(ctx, q, g.Name, g.Source, g.ExternalURL, nullInt(g.ExternalId), nullTime(g.ReleaseDate)).Scan(&gameId); err != nil {
+		return -1, e.Wrap("can't add game", err)
+	}
+
+	return gameId, nil
+}
+
+func (s *Storage) getOrCreateGame(ctx context.Context, g *storage.Game) (int, error) {
+	gameId, err := s.gameId(ctx, g)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return -1, err
+	}
+
+	if gameId < 0 {
+		gameId, err = s.addGame(ctx, g)
+		if err != nil {
+			return -1, err
+		}
+	} else {
+		if !g.ReleaseDate.IsZero() {
+			if err := s.fillReleaseDate(ctx, gameId, g.ReleaseDate); err != nil {
+				return -1, err
+			}
+		}
+		if g.ExternalId != 0 {
+			if err := s.fillExternalId(ctx, gameId, g.ExternalId); err != nil {
+				return -1, err
+			}
+		}
+	}
+
+	return gameId, nil
+}
+
+func (s *Storage) fillReleaseDate(ctx context.Context, gameId int, releaseDate time.Time) error {
+	//Дата выхода уже сохранённой игры не перезаписывается
+	q := `UPDATE game SET release_date = $1 WHERE id = $2 AND release_date IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, q, releaseDate, gameId); err != nil {
+		return e.Wrap("can't fill game release date", err)
+	}
+
+	return nil
+}
+
+// fillExternalId дописывает id игры в источнике тем играм, что были добавлены до его появления
+func (s *Storage) fillExternalId(ctx context.Context, gameId int, externalId int) error {
+	q := `UPDATE game SET external_id = $1 WHERE id = $2 AND external_id IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, q, externalId, gameId); err != nil {
+		return e.Wrap("can't fill game external id", err)
+	}
+
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
+
+func (s *Storage) getWishlistFromQuery(ctx context.Context, query string, args ...any) ([]storage.Wishlist, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, e.Wrap("can't select wishlist", err)
+	}
+	defer rows.Close()
+
+	var wishlist []storage.Wishlist
+
+	for rows.Next() {
+		var w storage.Wishlist
+		var expectedReleaseDate sql.NullTime
+		var createdDate sql.NullTime
+		var notifiedDate sql.NullTime
+
+		var g storage.Game
+		var externalURL sql.NullString
+		var externalId sql.NullInt64
+		var releaseDate sql.NullTime
+
+		var u storage.User
+		var addedById sql.NullInt64
+		var addedByTelegramId sql.NullInt64
+		var addedByName sql.NullString
+
+		err = rows.Scan(&w.Id, &expectedReleaseDate, &notifiedDate, &createdDate, &g.Id, &g.Name, &g.Source, &externalURL, &externalId, &releaseDate, &u.Id, &u.TelegramId, &u.Name, &u.ChatId, &addedById, &addedByTelegramId, &addedByName)
+		if err != nil {
+			return nil, e.Wrap("can't scan game", err)
+		}
+
+		if createdDate.Valid {
+			w.AddedAt = createdDate.Time
+		}
+		if notifiedDate.Valid {
+			w.NotifiedAt = notifiedDate.Time
+		}
+		if expectedReleaseDate.Valid {
+			w.NotificationDate = expectedReleaseDate.Time
+		}
+		if externalURL.Valid {
+			g.ExternalURL = externalURL.String
+		}
+		if externalId.Valid {
+			g.ExternalId = int(externalId.Int64)
+		}
+		if releaseDate.Valid {
+			g.ReleaseDate = releaseDate.Time
+		}
+
+		w.Game = &g
+		w.User = &u
+		if addedById.Valid {
+			w.AddedBy = &storage.User{
+				Id:         int(addedById.Int64),
+				TelegramId: int(addedByTelegramId.Int64),
+				Name:       addedByName.String,
+			}
+		}
+
+		wishlist = append(wishlist, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, e.Wrap("rows iteration error", err)
+	}
+
+	if err := s.fillPlatforms(ctx, wishlist); err != nil {
+		return nil, err
+	}
+
+	return wishlist, nil
+}
+
+func addPlatforms(ctx context.Context, tx *sql.Tx, wishlistId int, platforms []storage.Platform) error {
+	q := `INSERT INTO wishlist_platform (wishlist_id, platform_id, name, release_date) VALUES ($1, $2, $3, $4)`
+
+	for _, p := range platforms {
+		if _, err := tx.ExecContext(ctx, q, wishlistId, p.Id, p.Name, nullTime(p.ReleaseDate)); err != nil {
+			return e.Wrap("can't add wishlist platform", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) fillPlatforms(ctx context.Context, wishlist []storage.Wishlist) error {
+	if len(wishlist) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(wishlist))
+	ids := make([]int64, 0, len(wishlist))
+	for i, w := range wishlist {
+		index[w.Id] = i
+		ids = append(ids, int64(w.Id))
+	}
+	args := []any{pq.Array(ids)}
+
+	q := `
+		SELECT wishlist_id, platform_id, name, release_date
+		FROM wishlist_platform
+		WHERE wishlist_id = ANY($1)
+		ORDER BY release_date ASC NULLS LAST, name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return e.Wrap("can't select wishlist platforms", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wishlistId int
+		var p storage.Platform
+		var releaseDate sql.NullTime
+
+		if err := rows.Scan(&wishlistId, &p.Id, &p.Name, &releaseDate); err != nil {
+			return e.Wrap("can't scan wishlist platform", err)
+		}
+		if releaseDate.Valid {
+			p.ReleaseDate = releaseDate.Time
+		}
+
+		i := index[wishlistId]
+		wishlist[i].Platforms = append(wishlist[i].Platforms, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return e.Wrap("rows iteration error", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Get(ctx context.Context, wishlistId int) (*storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.id = $1
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q, wishlistId)
+	if err != nil {
+		return nil, e.Wrap("can't get wishlist", err)
+	}
+	if len(wishlist) == 0 {
+		return nil, storage.ErrNoWishlist
+	}
+
+	return &wishlist[0], nil
+}
+
+func (s *Storage) GetAll(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.user_id = $1
+		ORDER BY g.name ASC
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q, u.Id)
+	if err != nil {
+		return nil, e.Wrap("can't get all games", err)
+	}
+
+	return wishlist, nil
+}
+
+// GetPage возвращает limit записей пользователя начиная с offset в том же порядке, что и GetAll
+func (s *Storage) GetPage(ctx context.Context, u *storage.User, limit int, offset int) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.user_id = $1
+		ORDER BY g.name ASC, w.id ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q, u.Id, limit, offset)
+	if err != nil {
+		return nil, e.Wrap("can't get page of games", err)
+	}
+
+	return wishlist, nil
+}
+
+func (s *Storage) Count(ctx context.Context, u *storage.User) (int, error) {
+	q := `SELECT COUNT(*) FROM wishlist WHERE user_id = $1`
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, q, u.Id).Scan(&count); err != nil {
+		return 0, e.Wrap("can't count games", err)
+	}
+
+	return count, nil
+}
+
+func (s *Storage) GetReleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.user_id = $1 AND g.release_date IS NOT NULL AND (g.release_date AT TIME ZONE 'UTC')::date <= (now() AT TIME ZONE 'UTC')::date
+		ORDER BY g.name ASC
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q, u.Id)
+	if err != nil {
+		return nil, e.Wrap("can't get released games", err)
+	}
+
+	return wishlist, nil
+}
+
+func (s *Storage) GetUnreleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.user_id = $1 AND (g.release_date IS NULL OR (g.release_date AT TIME ZONE 'UTC')::date > (now() AT TIME ZONE 'UTC')::date)
+		ORDER BY g.release_date ASC NULLS LAST, g.name ASC
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q, u.Id)
+	if err != nil {
+		return nil, e.Wrap("can't get unreleased games", err)
+	}
+
+	return wishlist, nil
+}
+
+func (s *Storage) Remove(ctx context.Context, wishlistId int) error {
+	q := `DELETE FROM wishlist WHERE id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, wishlistId)
+	if err != nil {
+		return e.Wrap("can't remove wishlist", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetToNotify(ctx context.Context) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN users u on w.user_id = u.id
+		LEFT JOIN users a on w.added_by = a.id
+		WHERE w.notified_at IS NULL AND w.notification_date IS NOT NULL AND (w.notification_date AT TIME ZONE 'UTC')::date <= (now() AT TIME ZONE 'UTC')::date
+	`
+
+	wishlist, err := s.getWishlistFromQuery(ctx, q)
+	if err != nil {
+		return nil, e.Wrap("can't get unreleased games", err)
+	}
+
+	return wishlist, nil
+}
+
+func (s *Storage) Notify(ctx context.Context, w *storage.Wishlist) error {
+	q := `UPDATE wishlist SET notified_at = now() WHERE id = $1 AND notified_at IS NULL`
+
+	_, err := s.db.ExecContext(ctx, q, w.Id)
+	if err != nil {
+		return e.Wrap("can't edit notify wishlist", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetState(ctx context.Context, telegramId int) (*storage.State, error) {
+	q := `SELECT telegram_id, step, game_name, created_at FROM user_state WHERE telegram_id = $1`
+
+	var state storage.State
+
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&state.TelegramId, &state.Step, &state.GameName, &state.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoState
+		}
+		return nil, e.Wrap("can't get state", err)
+	}
+
+	return &state, nil
+}
+
+func (s *Storage) SaveState(ctx context.Context, state *storage.State) error {
+	q := `
+		INSERT INTO user_state (telegram_id, step, game_name, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (telegram_id) DO UPDATE SET step = excluded.step, game_name = excluded.game_name, created_at = excluded.created_at
+	`
+
+	_, err := s.db.ExecContext(ctx, q, state.TelegramId, state.Step, state.GameName, state.CreatedAt)
+	if err != nil {
+		return e.Wrap("can't save state", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveState(ctx context.Context, telegramId int) error {
+	q := `DELETE FROM user_state WHERE telegram_id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, telegramId)
+	if err != nil {
+		return e.Wrap("can't remove state", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	q := `INSERT INTO wishlist_share (token, telegram_id, created_at) VALUES ($1, $2, $3)`
+
+	_, err := s.db.ExecContext(ctx, q, share.Token, share.TelegramId, share.CreatedAt)
+	if err != nil {
+		return e.Wrap("can't save share", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetShare(ctx context.Context, token string) (*storage.Share, error) {
+	q := `SELECT token, telegram_id, created_at FROM wishlist_share WHERE token = $1`
+
+	var share storage.Share
+
+	err := s.db.QueryRowContext(ctx, q, token).Scan(&share.Token, &share.TelegramId, &share.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoShare
+		}
+		return nil, e.Wrap("can't get share", err)
+	}
+
+	return &share, nil
+}
+
+func (s *Storage) RemoveShares(ctx context.Context, telegramId int) error {
+	q := `DELETE FROM wishlist_share WHERE telegram_id = $1`
+
+	_, err := s.db.ExecContext(ctx, q, telegramId)
+	if err != nil {
+		return e.Wrap("can't remove shares", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetCache(ctx context.Context, key string) (*storage.CacheEntry, error) {
+	q := `SELECT key, value, expires_at FROM api_cache WHERE key = $1`
+
+	var entry storage.CacheEntry
+
+	err := s.db.QueryRowContext(ctx, q, key).Scan(&entry.Key, &entry.Value, &entry.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoCache
+		}
+		return nil, e.Wrap("can't get cache", err)
+	}
+
+	return &entry, nil
+}
+
+func (s *Storage) SaveCache(ctx context.Context, entry *storage.CacheEntry) error {
+	q := `
+		INSERT INTO api_cache (key, value, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+	`
+
+	if _, err := s.db.ExecContext(ctx, q, entry.Key, entry.Value, entry.ExpiresAt); err != nil {
+		return e.Wrap("can't save cache", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveCacheBefore(ctx context.Context, before time.Time) error {
+	q := `DELETE FROM api_cache WHERE expires_at < $1`
+
+	if _, err := s.db.ExecContext(ctx, q, before); err != nil {
+		return e.Wrap("can't remove expired cache", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetOffset(ctx context.Context) (int, error) {
+	q := `SELECT value FROM update_offset WHERE id = 1`
+
+	var offset int
+
+	err := s.db.QueryRowContext(ctx, q).Scan(&offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, e.Wrap("can't get updates offset", err)
+	}
+
+	return offset, nil
+}
+
+func (s *Storage) SaveOffset(ctx context.Context, offset int) (err error) {
+	defer func() { err = e.WrapIfNil("can't save updates offset", err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := `
+		INSERT INTO update_offset (id, value) VALUES (1, $1)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value
+	`
+	if _, err := tx.ExecContext(ctx, q, offset); err != nil {
+		return err
+	}
+
+	//Обновления до offset Telegram больше не пришлёт, их можно не хранить
+	q = `DELETE FROM processed_update WHERE update_id < $1`
+	if _, err := tx.ExecContext(ctx, q, offset); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) IsProcessed(ctx context.Context, updateId int) (bool, error) {
+	q := `SELECT COUNT(*) FROM processed_update WHERE update_id = $1`
+
+	var count int
+
+	if err := s.db.QueryRowContext(ctx, q, updateId).Scan(&count); err != nil {
+		return false, e.Wrap("can't check if update processed", err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *Storage) MarkProcessed(ctx context.Context, updateId int) error {
+	q := `INSERT INTO processed_update (update_id) VALUES ($1) ON CONFLICT (update_id) DO NOTHING`
+
+	if _, err := s.db.ExecContext(ctx, q, updateId); err != nil {
+		return e.Wrap("can't mark update processed", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveProcessedBefore(ctx context.Context, before time.Time) error {
+	q := `DELETE FROM processed_update WHERE processed_at < $1`
+
+	if _, err := s.db.ExecContext(ctx, q, before); err != nil {
+		return e.Wrap("can't remove processed updates", err)
+	}
+
+	return nil
+}
+
+func New(dsn string) (*Storage, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, e.Wrap("can't open database", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, e.Wrap("can't connect to database", err)
+	}
+
+	return &Storage{db: db}, nil
+}
+
+func (s *Storage) Init(ctx context.Context) error {
+	if err := migrate.Up(ctx, s.db, migrations); err != nil {
+		return e.Wrap("can't init database", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package sqlite
+
+import "tg_game_wishlist/storage/migrate"
+
+// Новые изменения схемы добавляются только в конец списка, уже выпущенные миграции не редактируются
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "init",
+		Up: `
+			CREATE TABLE IF NOT EXISTS user (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name VARCHAR(255) NOT NULL,
+				chat_id INTEGER NOT NULL,
+
+				UNIQUE(name, chat_id)
+			);
+
+			CREATE TABLE IF NOT EXISTS game (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				external_url VARCHAR(500) NULL,
+				source VARCHAR(255) NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+				UNIQUE(source, external_url, name)
+			);
+
+			CREATE TABLE IF NOT EXISTS wishlist (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				game_id INTEGER NOT NULL,
+				notification_date DATETIME NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				notified_at DATETIME NULL,
+
+				FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+				FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE,
+
+				UNIQUE(user_id, game_id)
+			);
+		`,
+	},
+	{
+		Version: 2,
+		Name:    "game_release_date",
+		Up: `
+			ALTER TABLE game ADD COLUMN release_date DATETIME NULL;
+
+			-- Для уже сохранённых игр берём самую раннюю дату уведомления
+			UPDATE game
+			SET release_date = (SELECT MIN(w.notification_date) FROM wishlist w WHERE w.game_id = game.id);
+		`,
+	},
+	{
+		Version: 3,
+		Name:    "user_telegram_id",
+		Up: `
+			-- id пользователя известен только для личных чатов: там он совпадает с id чата,
+			-- поэтому строки одного чата (например, после смены username) объединяются в одного пользователя.
+			-- Строка группы переносится в личный список участника с тем же username,
+			-- а без такого участника остаётся общим списком группы с telegram_id = id чата
+			CREATE TABLE user_merge (
+				id INTEGER PRIMARY KEY,
+				target_id INTEGER NOT NULL
+			);
+
+			INSERT INTO user_merge (id, target_id)
+			SELECT u.id, COALESCE(
+				CASE WHEN u.chat_id < 0 THEN (
+					SELECT MIN(p2.id)
+					FROM user p2
+					WHERE p2.chat_id = (
+						SELECT p.chat_id FROM user p WHERE p.chat_id > 0 AND p.name = u.name ORDER BY p.id DESC LIMIT 1
+					)
+				) END,
+				(SELECT MIN(u2.id) FROM user u2 WHERE u2.chat_id = u.chat_id)
+			)
+			FROM user u;
+
+			DELETE FROM wishlist WHERE user_id NOT IN (SELECT id FROM user_merge);
+
+			-- Из одинаковых игр объединяемых строк остаётся добавленная первой
+			DELETE FROM wishlist
+			WHERE EXISTS (
+				SELECT 1
+				FROM wishlist w2
+				INNER JOIN user_merge m2 ON m2.id = w2.user_id
+				INNER JOIN user_merge m1 ON m1.id = wishlist.user_id
+				WHERE m2.target_id = m1.target_id AND w2.game_id = wishlist.game_id AND w2.id < wishlist.id
+			);
+
+			UPDATE wishlist
+			SET user_id = (SELECT m.target_id FROM user_merge m WHERE m.id = wishlist.user_id);
+
+			CREATE TABLE user_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				telegram_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				chat_id INTEGER NOT NULL,
+
+				UNIQUE(telegram_id)
+			);
+
+			-- У списка группы нет имени пользователя, как и у списков групп, которые создаёт бот
+			INSERT INTO user_new (id, telegram_id, name, chat_id)
+			SELECT u.id, u.chat_id, CASE WHEN u.chat_id > 0 THEN (SELECT u2.name FROM user u2 WHERE u2.chat_id = u.chat_id ORDER BY u2.id DESC LIMIT 1) ELSE '' END, u.chat_id
+			FROM user u
+			WHERE u.id IN (SELECT target_id FROM user_merge);
+
+			DROP TABLE user_merge;
+			DROP TABLE user;
+			ALTER TABLE user_new RENAME TO user;
+		`,
+	},
+	{
+		Version: 4,
+		Name:    "wishlist_platform",
+		Up: `
+			CREATE TABLE wishlist_platform (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				wishlist_id INTEGER NOT NULL,
+				platform_id INTEGER NOT NULL,
+				name VARCHAR(255) NOT NULL,
+				release_date DATETIME NULL,
+
+				FOREIGN KEY (wishlist_id) REFERENCES wishlist(id) ON DELETE CASCADE,
+
+				UNIQUE(wishlist_id, platform_id)
+			);
+		`,
+	},
+	{
+		Version: 5,
+		Name:    "user_state",
+		Up: `
+			CREATE TABLE user_state (
+				telegram_id INTEGER PRIMARY KEY,
+				step VARCHAR(255) NOT NULL,
+				game_name VARCHAR(255) NOT NULL,
+				created_at DATETIME NOT NULL
+			);
+		`,
+	},
+	{
+		Version: 6,
+		Name:    "telegram_updates",
+		Up: `
+			CREATE TABLE update_offset (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				value INTEGER NOT NULL
+			);
+
+			CREATE TABLE processed_update (
+				update_id INTEGER PRIMARY KEY,
+				processed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			);
+		`,
+	},
+	{
+		Version: 7,
+		Name:    "game_external_id",
+		Up: `
+			ALTER TABLE game ADD COLUMN external_id INTEGER NULL;
+		`,
+	},
+	{
+		Version: 8,
+		Name:    "wishlist_added_by",
+		Up: `
+			ALTER TABLE wishlist ADD COLUMN added_by INTEGER NULL REFERENCES user(id);
+		`,
+	},
+	{
+		Version: 9,
+		Name:    "wishlist_share",
+		Up: `
+			CREATE TABLE wishlist_share (
+				token VARCHAR(64) PRIMARY KEY,
+				telegram_id INTEGER NOT NULL,
+				created_at DATETIME NOT NULL
+			);
+
+			CREATE INDEX wishlist_share_telegram_id ON wishlist_share (telegram_id);
+		`,
+	},
+	{
+		Version: 10,
+		Name:    "api_cache",
+		Up: `
+			CREATE TABLE api_cache (
+				key VARCHAR(255) PRIMARY KEY,
+				value BLOB NOT NULL,
+				expires_at DATETIME NOT NULL
+			);
+		`,
+	},
+}
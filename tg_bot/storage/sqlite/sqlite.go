@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"tg_game_wishlist/lib/e"
 	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/migrate"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -17,7 +20,7 @@ type Storage struct {
 func (s *Storage) IsExists(ctx context.Context, w *storage.Wishlist) (res bool, err error) {
 	defer func() { err = e.WrapIfNil("can't check if exists wishlist", err) }()
 
-	userId, err := s.userId(ctx, w.User.Name)
+	userId, err := s.userId(ctx, w.User.TelegramId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -52,17 +55,16 @@ func (s *Storage) IsExists(ctx context.Context, w *storage.Wishlist) (res bool,
 	return count > 0, nil
 }
 
-func (s *Storage) GetUserByName(ctx context.Context, userName string) (*storage.User, error) {
+func (s *Storage) GetUserByTelegramId(ctx context.Context, telegramId int) (*storage.User, error) {
 	q := `
-		SELECT id, name
+		SELECT id, telegram_id, name, chat_id
 		FROM user 
-		WHERE name = ?
+		WHERE telegram_id = ?
 	`
 
-	var id int
-	var name string
+	var u storage.User
 
-	err := s.db.QueryRowContext(ctx, q, userName).Scan(&id, &name)
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&u.Id, &u.TelegramId, &u.Name, &u.ChatId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, storage.ErrNoUser
@@ -70,16 +72,13 @@ func (s *Storage) GetUserByName(ctx context.Context, userName string) (*storage.
 		return nil, err
 	}
 
-	return &storage.User{
-		Id:   id,
-		Name: name,
-	}, nil
+	return &u, nil
 }
 
 func (s *Storage) Add(ctx context.Context, w *storage.Wishlist) (err error) {
 	defer func() { err = e.WrapIfNil("can't add wishlist", err) }()
 	//Получение или создание пользователя
-	userId, err := s.getOrCreateUser(ctx, w.User.Name, w.User.ChatId)
+	userId, err := s.getOrCreateUser(ctx, w.User)
 	if err != nil {
 		return err
 	}
@@ -92,28 +91,130 @@ func (s *Storage) Add(ctx context.Context, w *storage.Wishlist) (err error) {
 	}
 	w.Game.Id = gameId
 
+	addedBy, err := s.addedBy(ctx, w)
+	if err != nil {
+		return err
+	}
+
 	var q string
 
 	if w.NotificationDate.IsZero() {
-		q = `INSERT INTO wishlist (game_id, user_id) VALUES (?,?)`
+		q = `INSERT INTO wishlist (game_id, user_id, added_by) VALUES (?,?,?)`
 	} else {
-		q = `INSERT INTO wishlist (game_id, user_id, notification_date) VALUES (?,?,?)`
+		q = `INSERT INTO wishlist (game_id, user_id, added_by, notification_date) VALUES (?,?,?,?)`
+	}
+
+	//Запись и её платформы добавляются вместе, чтобы не остаться без платформ
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	_, err = s.db.ExecContext(ctx, q, gameId, userId, w.NotificationDate)
+	res, err := tx.ExecContext(ctx, q, gameId, userId, addedBy, w.NotificationDate)
 	if err != nil {
 		return err
 	}
 
+	wishlistId, err := res.LastInsertId()
+	if err != nil {
+		return e.Wrap("can't get last wishlist id", err)
+	}
+
+	if err := addPlatforms(ctx, tx, int(wishlistId), w.Platforms); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	w.Id = int(wishlistId)
+
 	return nil
 }
 
-func (s *Storage) userId(ctx context.Context, userName string) (int, error) {
-	q := `SELECT id FROM user WHERE name = ?`
+// addedBy id участника, добавившего игру в список группы; в своём списке пользователь не отмечается
+func (s *Storage) addedBy(ctx context.Context, w *storage.Wishlist) (sql.NullInt64, error) {
+	if w.AddedBy == nil || w.AddedBy.TelegramId == w.User.TelegramId {
+		return sql.NullInt64{}, nil
+	}
+
+	userId, err := s.getOrCreateUser(ctx, w.AddedBy)
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	w.AddedBy.Id = userId
+
+	return sql.NullInt64{Int64: int64(userId), Valid: true}, nil
+}
+
+// MigrateChat переносит список желаемого группы в супергруппу, в которую она превратилась.
+// Если в супергруппе уже успели что-то добавить, списки объединяются.
+func (s *Storage) MigrateChat(ctx context.Context, oldChatId int, newChatId int) (err error) {
+	defer func() { err = e.WrapIfNil("can't migrate chat", err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	//Ссылки на список переходят к супергруппе вместе с ним
+	q := `UPDATE wishlist_share SET telegram_id = ? WHERE telegram_id = ?`
+	if _, err := tx.ExecContext(ctx, q, newChatId, oldChatId); err != nil {
+		return err
+	}
+
+	var oldId int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM user WHERE telegram_id = ?`, oldChatId).Scan(&oldId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	var newId int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM user WHERE telegram_id = ?`, newChatId).Scan(&newId)
+	if errors.Is(err, sql.ErrNoRows) {
+		q = `UPDATE user SET telegram_id = ?, chat_id = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, q, newChatId, newChatId, oldId); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	if err != nil {
+		return err
+	}
+
+	q = `UPDATE wishlist SET user_id = ? WHERE user_id = ? AND game_id NOT IN (SELECT game_id FROM wishlist WHERE user_id = ?)`
+	if _, err := tx.ExecContext(ctx, q, newId, oldId, newId); err != nil {
+		return err
+	}
+
+	//Игры, которые уже есть в списке супергруппы, удаляются вместе со старой группой
+	q = `DELETE FROM wishlist_platform WHERE wishlist_id IN (SELECT id FROM wishlist WHERE user_id = ?)`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+	q = `DELETE FROM wishlist WHERE user_id = ?`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+	q = `DELETE FROM user WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, q, oldId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) userId(ctx context.Context, telegramId int) (int, error) {
+	q := `SELECT id FROM user WHERE telegram_id = ?`
 
 	var id int
 
-	err := s.db.QueryRowContext(ctx, q, userName).Scan(&id)
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&id)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return -1, err
@@ -124,10 +225,10 @@ func (s *Storage) userId(ctx context.Context, userName string) (int, error) {
 	return id, nil
 }
 
-func (s *Storage) addUser(ctx context.Context, userName string, chatId int) (int, error) {
-	q := `INSERT INTO user (name, chat_id) VALUES(?, ?)`
+func (s *Storage) addUser(ctx context.Context, u *storage.User) (int, error) {
+	q := `INSERT INTO user (telegram_id, name, chat_id) VALUES(?, ?, ?)`
 
-	res, err := s.db.ExecContext(ctx, q, userName, chatId)
+	res, err := s.db.ExecContext(ctx, q, u.TelegramId, u.Name, u.ChatId)
 	if err != nil {
 		return -1, e.Wrap("can't create user", err)
 	}
@@ -140,17 +241,29 @@ func (s *Storage) addUser(ctx context.Context, userName string, chatId int) (int
 	return int(userId), nil
 }
 
-func (s *Storage) getOrCreateUser(ctx context.Context, userName string, chatId int) (int, error) {
-	userId, err := s.userId(ctx, userName)
+func (s *Storage) refreshUser(ctx context.Context, userId int, u *storage.User) error {
+	//username и чат могут меняться, пользователь определяется только по telegram_id
+	q := `UPDATE user SET name = ?, chat_id = ? WHERE id = ? AND (name <> ? OR chat_id <> ?)`
+
+	if _, err := s.db.ExecContext(ctx, q, u.Name, u.ChatId, userId, u.Name, u.ChatId); err != nil {
+		return e.Wrap("can't refresh user", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) getOrCreateUser(ctx context.Context, u *storage.User) (int, error) {
+	userId, err := s.userId(ctx, u.TelegramId)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return -1, err
 	}
 
 	if userId < 0 {
-		userId, err = s.addUser(ctx, userName, chatId)
-		if err != nil {
-			return -1, err
-		}
+		return s.addUser(ctx, u)
+	}
+
+	if err := s.refreshUser(ctx, userId, u); err != nil {
+		return -1, err
 	}
 
 	return userId, nil
@@ -174,9 +287,9 @@ func (s *Storage) gameId(ctx context.Context, g *storage.Game) (int, error) {
 }
 
 func (s *Storage) addGame(ctx context.Context, g *storage.Game) (int, error) {
-	q := `INSERT INTO game (name, source, external_url) VALUES(?,?,?)`
+	q := `INSERT INTO game (name, source, external_url, external_id, release_date) VALUES(?,?,?,?,?)`
 
-	res, err := s.db.ExecContext(ctx, q, g.Name, g.Source, g.ExternalURL)
+	res, err := s.db.ExecContext(ctx, q, g.Name, g.Source, g.ExternalURL, nullInt(g.ExternalId), nullTime(g.ReleaseDate))
 	if err != nil {
 		return -1, e.Wrap("can't add game", err)
 	}
@@ -200,11 +313,52 @@ func (s *Storage) getOrCreateGame(ctx context.Context, g *storage.Game) (int, er
 		if err != nil {
 			return -1, err
 		}
+	} else {
+		if !g.ReleaseDate.IsZero() {
+			if err := s.fillReleaseDate(ctx, gameId, g.ReleaseDate); err != nil {
+				return -1, err
+			}
+		}
+		if g.ExternalId != 0 {
+			if err := s.fillExternalId(ctx, gameId, g.ExternalId); err != nil {
+				return -1, err
+			}
+		}
 	}
 
 	return gameId, nil
 }
 
+func (s *Storage) fillReleaseDate(ctx context.Context, gameId int, releaseDate time.Time) error {
+	//Дата выхода уже сохранённой игры не перезаписывается
+	q := `UPDATE game SET release_date = ? WHERE id = ? AND release_date IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, q, releaseDate, gameId); err != nil {
+		return e.Wrap("can't fill game release date", err)
+	}
+
+	return nil
+}
+
+// fillExternalId дописывает id игры в источнике тем играм, что были добавлены до его появления
+func (s *Storage) fillExternalId(ctx context.Context, gameId int, externalId int) error {
+	q := `UPDATE game SET external_id = ? WHERE id = ? AND external_id IS NULL`
+
+	if _, err := s.db.ExecContext(ctx, q, externalId, gameId); err != nil {
+		return e.Wrap("can't fill game external id", err)
+	}
+
+	return nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullInt(i int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(i), Valid: i != 0}
+}
+
 func (s *Storage) getWishlistFromSqliteQuery(ctx context.Context, query string, args ...any) ([]storage.Wishlist, error) {
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -222,10 +376,15 @@ func (s *Storage) getWishlistFromSqliteQuery(ctx context.Context, query string,
 
 		var g storage.Game
 		var externalURL sql.NullString
+		var externalId sql.NullInt64
+		var releaseDate sql.NullTime
 
 		var u storage.User
+		var addedById sql.NullInt64
+		var addedByTelegramId sql.NullInt64
+		var addedByName sql.NullString
 
-		err = rows.Scan(&w.Id, &expectedReleaseDate, &notifiedDate, &createdDate, &g.Id, &g.Name, &g.Source, &externalURL, &u.Id, &u.Name, &u.ChatId)
+		err = rows.Scan(&w.Id, &expectedReleaseDate, &notifiedDate, &createdDate, &g.Id, &g.Name, &g.Source, &externalURL, &externalId, &releaseDate, &u.Id, &u.TelegramId, &u.Name, &u.ChatId, &addedById, &addedByTelegramId, &addedByName)
 		if err != nil {
 			return nil, e.Wrap("can't scan game", err)
 		}
@@ -242,9 +401,22 @@ func (s *Storage) getWishlistFromSqliteQuery(ctx context.Context, query string,
 		if externalURL.Valid {
 			g.ExternalURL = externalURL.String
 		}
+		if externalId.Valid {
+			g.ExternalId = int(externalId.Int64)
+		}
+		if releaseDate.Valid {
+			g.ReleaseDate = releaseDate.Time
+		}
 
 		w.Game = &g
 		w.User = &u
+		if addedById.Valid {
+			w.AddedBy = &storage.User{
+				Id:         int(addedById.Int64),
+				TelegramId: int(addedByTelegramId.Int64),
+				Name:       addedByName.String,
+			}
+		}
 
 		wishlist = append(wishlist, w)
 	}
@@ -253,15 +425,101 @@ func (s *Storage) getWishlistFromSqliteQuery(ctx context.Context, query string,
 		return nil, e.Wrap("rows iteration error", err)
 	}
 
+	if err := s.fillPlatforms(ctx, wishlist); err != nil {
+		return nil, err
+	}
+
 	return wishlist, nil
 }
 
+func addPlatforms(ctx context.Context, tx *sql.Tx, wishlistId int, platforms []storage.Platform) error {
+	q := `INSERT INTO wishlist_platform (wishlist_id, platform_id, name, release_date) VALUES (?,?,?,?)`
+
+	for _, p := range platforms {
+		if _, err := tx.ExecContext(ctx, q, wishlistId, p.Id, p.Name, nullTime(p.ReleaseDate)); err != nil {
+			return e.Wrap("can't add wishlist platform", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) fillPlatforms(ctx context.Context, wishlist []storage.Wishlist) error {
+	if len(wishlist) == 0 {
+		return nil
+	}
+
+	index := make(map[int]int, len(wishlist))
+	args := make([]any, 0, len(wishlist))
+	for i, w := range wishlist {
+		index[w.Id] = i
+		args = append(args, w.Id)
+	}
+
+	q := `
+		SELECT wishlist_id, platform_id, name, release_date
+		FROM wishlist_platform
+		WHERE wishlist_id IN (?` + strings.Repeat(",?", len(args)-1) + `)
+		ORDER BY release_date ASC, name ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return e.Wrap("can't select wishlist platforms", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var wishlistId int
+		var p storage.Platform
+		var releaseDate sql.NullTime
+
+		if err := rows.Scan(&wishlistId, &p.Id, &p.Name, &releaseDate); err != nil {
+			return e.Wrap("can't scan wishlist platform", err)
+		}
+		if releaseDate.Valid {
+			p.ReleaseDate = releaseDate.Time
+		}
+
+		i := index[wishlistId]
+		wishlist[i].Platforms = append(wishlist[i].Platforms, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return e.Wrap("rows iteration error", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Get(ctx context.Context, wishlistId int) (*storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN user u on w.user_id = u.id
+		LEFT JOIN user a on w.added_by = a.id
+		WHERE w.id = ?
+	`
+
+	wishlist, err := s.getWishlistFromSqliteQuery(ctx, q, wishlistId)
+	if err != nil {
+		return nil, e.Wrap("can't get wishlist", err)
+	}
+	if len(wishlist) == 0 {
+		return nil, storage.ErrNoWishlist
+	}
+
+	return &wishlist[0], nil
+}
+
 func (s *Storage) GetAll(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
 	q := `
-		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, u.id, u.name, u.chat_id
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
 		FROM wishlist w
 		INNER JOIN game g ON w.game_id = g.id
 		INNER JOIN user u on w.user_id = u.id
+		LEFT JOIN user a on w.added_by = a.id
 		WHERE w.user_id = ?
 		ORDER BY g.name ASC
 	`
@@ -274,13 +532,46 @@ func (s *Storage) GetAll(ctx context.Context, u *storage.User) ([]storage.Wishli
 	return wishlist, nil
 }
 
+// GetPage возвращает limit записей пользователя начиная с offset в том же порядке, что и GetAll
+func (s *Storage) GetPage(ctx context.Context, u *storage.User, limit int, offset int) ([]storage.Wishlist, error) {
+	q := `
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
+		FROM wishlist w
+		INNER JOIN game g ON w.game_id = g.id
+		INNER JOIN user u on w.user_id = u.id
+		LEFT JOIN user a on w.added_by = a.id
+		WHERE w.user_id = ?
+		ORDER BY g.name ASC, w.id ASC
+		LIMIT ? OFFSET ?
+	`
+
+	wishlist, err := s.getWishlistFromSqliteQuery(ctx, q, u.Id, limit, offset)
+	if err != nil {
+		return nil, e.Wrap("can't get page of games", err)
+	}
+
+	return wishlist, nil
+}
+
+func (s *Storage) Count(ctx context.Context, u *storage.User) (int, error) {
+	q := `SELECT COUNT(*) FROM wishlist WHERE user_id = ?`
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, q, u.Id).Scan(&count); err != nil {
+		return 0, e.Wrap("can't count games", err)
+	}
+
+	return count, nil
+}
+
 func (s *Storage) GetReleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
 	q := `
-		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, u.id, u.name, u.chat_id
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
 		FROM wishlist w
 		INNER JOIN game g ON w.game_id = g.id
 		INNER JOIN user u on w.user_id = u.id
-		WHERE w.user_id = ? AND g.release_date <= date('now')
+		LEFT JOIN user a on w.added_by = a.id
+		WHERE w.user_id = ? AND g.release_date IS NOT NULL AND date(g.release_date) <= date('now')
 		ORDER BY g.name ASC
 	`
 
@@ -294,12 +585,13 @@ func (s *Storage) GetReleased(ctx context.Context, u *storage.User) ([]storage.W
 
 func (s *Storage) GetUnreleased(ctx context.Context, u *storage.User) ([]storage.Wishlist, error) {
 	q := `
-		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, u.id, u.name, u.chat_id
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
 		FROM wishlist w
 		INNER JOIN game g ON w.game_id = g.id
 		INNER JOIN user u on w.user_id = u.id
-		WHERE w.user_id = ? AND g.release_date > date('now')
-		ORDER BY g.name ASC
+		LEFT JOIN user a on w.added_by = a.id
+		WHERE w.user_id = ? AND (g.release_date IS NULL OR date(g.release_date) > date('now'))
+		ORDER BY g.release_date IS NULL, g.release_date ASC, g.name ASC
 	`
 
 	wishlist, err := s.getWishlistFromSqliteQuery(ctx, q, u.Id)
@@ -310,25 +602,37 @@ func (s *Storage) GetUnreleased(ctx context.Context, u *storage.User) ([]storage
 	return wishlist, nil
 }
 
-func (s *Storage) Remove(ctx context.Context, wishlistId int) error {
-	q := `DELETE FROM wishlist WHERE id = ?`
+// Remove удаляет игру из списка вместе с её платформами: внешние ключи в sqlite выключены, и ON DELETE CASCADE не срабатывает
+func (s *Storage) Remove(ctx context.Context, wishlistId int) (err error) {
+	defer func() { err = e.WrapIfNil("can't remove wishlist", err) }()
 
-	_, err := s.db.ExecContext(ctx, q, wishlistId)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return e.Wrap("can't remove wishlist", err)
+		return err
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	return nil
+	q := `DELETE FROM wishlist_platform WHERE wishlist_id = ?`
+	if _, err := tx.ExecContext(ctx, q, wishlistId); err != nil {
+		return err
+	}
+	q = `DELETE FROM wishlist WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, q, wishlistId); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *Storage) GetToNotify(ctx context.Context) ([]storage.Wishlist, error) {
 	q := `
-		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, u.id, u.name, u.chat_id
+		SELECT w.id, w.notification_date, w.notified_at, w.created_at, g.id, g.name, g.source, g.external_url, g.external_id, g.release_date, u.id, u.telegram_id, u.name, u.chat_id, a.id, a.telegram_id, a.name
 		FROM wishlist w
 		INNER JOIN game g ON w.game_id = g.id
 		INNER JOIN user u on w.user_id = u.id
-		WHERE w.notified_at IS NULL AND w.notification_date IS NOT NULL AND date('now') >= w.notification_date
-    `
+		LEFT JOIN user a on w.added_by = a.id
+		WHERE w.notified_at IS NULL AND w.notification_date IS NOT NULL AND date(w.notification_date) <= date('now')
+	`
 
 	wishlist, err := s.getWishlistFromSqliteQuery(ctx, q)
 	if err != nil {
@@ -339,7 +643,7 @@ func (s *Storage) GetToNotify(ctx context.Context) ([]storage.Wishlist, error) {
 }
 
 func (s *Storage) Notify(ctx context.Context, w *storage.Wishlist) error {
-	q := `UPDATE wishlist SET notified_at = date('now') WHERE id = ?`
+	q := `UPDATE wishlist SET notified_at = date('now') WHERE id = ? AND notified_at IS NULL`
 
 	_, err := s.db.ExecContext(ctx, q, w.Id)
 	if err != nil {
@@ -349,6 +653,201 @@ func (s *Storage) Notify(ctx context.Context, w *storage.Wishlist) error {
 	return nil
 }
 
+func (s *Storage) GetState(ctx context.Context, telegramId int) (*storage.State, error) {
+	q := `SELECT telegram_id, step, game_name, created_at FROM user_state WHERE telegram_id = ?`
+
+	var state storage.State
+
+	err := s.db.QueryRowContext(ctx, q, telegramId).Scan(&state.TelegramId, &state.Step, &state.GameName, &state.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoState
+		}
+		return nil, e.Wrap("can't get state", err)
+	}
+
+	return &state, nil
+}
+
+func (s *Storage) SaveState(ctx context.Context, state *storage.State) error {
+	q := `
+		INSERT INTO user_state (telegram_id, step, game_name, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (telegram_id) DO UPDATE SET step = excluded.step, game_name = excluded.game_name, created_at = excluded.created_at
+	`
+
+	_, err := s.db.ExecContext(ctx, q, state.TelegramId, state.Step, state.GameName, state.CreatedAt)
+	if err != nil {
+		return e.Wrap("can't save state", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveState(ctx context.Context, telegramId int) error {
+	q := `DELETE FROM user_state WHERE telegram_id = ?`
+
+	_, err := s.db.ExecContext(ctx, q, telegramId)
+	if err != nil {
+		return e.Wrap("can't remove state", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) SaveShare(ctx context.Context, share *storage.Share) error {
+	q := `INSERT INTO wishlist_share (token, telegram_id, created_at) VALUES (?, ?, ?)`
+
+	_, err := s.db.ExecContext(ctx, q, share.Token, share.TelegramId, share.CreatedAt)
+	if err != nil {
+		return e.Wrap("can't save share", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetShare(ctx context.Context, token string) (*storage.Share, error) {
+	q := `SELECT token, telegram_id, created_at FROM wishlist_share WHERE token = ?`
+
+	var share storage.Share
+
+	err := s.db.QueryRowContext(ctx, q, token).Scan(&share.Token, &share.TelegramId, &share.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoShare
+		}
+		return nil, e.Wrap("can't get share", err)
+	}
+
+	return &share, nil
+}
+
+func (s *Storage) RemoveShares(ctx context.Context, telegramId int) error {
+	q := `DELETE FROM wishlist_share WHERE telegram_id = ?`
+
+	_, err := s.db.ExecContext(ctx, q, telegramId)
+	if err != nil {
+		return e.Wrap("can't remove shares", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetCache(ctx context.Context, key string) (*storage.CacheEntry, error) {
+	q := `SELECT key, value, expires_at FROM api_cache WHERE key = ?`
+
+	var entry storage.CacheEntry
+
+	err := s.db.QueryRowContext(ctx, q, key).Scan(&entry.Key, &entry.Value, &entry.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrNoCache
+		}
+		return nil, e.Wrap("can't get cache", err)
+	}
+
+	return &entry, nil
+}
+
+func (s *Storage) SaveCache(ctx context.Context, entry *storage.CacheEntry) error {
+	q := `
+		INSERT INTO api_cache (key, value, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at
+	`
+
+	_, err := s.db.ExecContext(ctx, q, entry.Key, entry.Value, entry.ExpiresAt)
+	if err != nil {
+		return e.Wrap("can't save cache", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveCacheBefore(ctx context.Context, before time.Time) error {
+	//expires_at записывает драйвер в виде строки с часовым поясом, сравниваем через julianday, который его учитывает
+	q := `DELETE FROM api_cache WHERE julianday(expires_at) < julianday(?, 'unixepoch')`
+
+	if _, err := s.db.ExecContext(ctx, q, before.UTC().Unix()); err != nil {
+		return e.Wrap("can't remove expired cache", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) GetOffset(ctx context.Context) (int, error) {
+	q := `SELECT value FROM update_offset WHERE id = 1`
+
+	var offset int
+
+	err := s.db.QueryRowContext(ctx, q).Scan(&offset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, e.Wrap("can't get updates offset", err)
+	}
+
+	return offset, nil
+}
+
+func (s *Storage) SaveOffset(ctx context.Context, offset int) (err error) {
+	defer func() { err = e.WrapIfNil("can't save updates offset", err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	q := `
+		INSERT INTO update_offset (id, value) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET value = excluded.value
+	`
+	if _, err := tx.ExecContext(ctx, q, offset); err != nil {
+		return err
+	}
+
+	//Обновления до offset Telegram больше не пришлёт, их можно не хранить
+	q = `DELETE FROM processed_update WHERE update_id < ?`
+	if _, err := tx.ExecContext(ctx, q, offset); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Storage) IsProcessed(ctx context.Context, updateId int) (bool, error) {
+	q := `SELECT COUNT(*) FROM processed_update WHERE update_id = ?`
+
+	var count int
+
+	if err := s.db.QueryRowContext(ctx, q, updateId).Scan(&count); err != nil {
+		return false, e.Wrap("can't check if update processed", err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *Storage) MarkProcessed(ctx context.Context, updateId int) error {
+	q := `INSERT INTO processed_update (update_id) VALUES (?) ON CONFLICT (update_id) DO NOTHING`
+
+	if _, err := s.db.ExecContext(ctx, q, updateId); err != nil {
+		return e.Wrap("can't mark update processed", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) RemoveProcessedBefore(ctx context.Context, before time.Time) error {
+	//processed_at заполняет CURRENT_TIMESTAMP в формате datetime(), сравниваем в нём же
+	q := `DELETE FROM processed_update WHERE processed_at < datetime(?, 'unixepoch')`
+
+	if _, err := s.db.ExecContext(ctx, q, before.UTC().Unix()); err != nil {
+		return e.Wrap("can't remove processed updates", err)
+	}
+
+	return nil
+}
+
 func New(path string) (*Storage, error) {
 	db, err := sql.Open("sqlite3", path)
 	if err != nil {
@@ -363,44 +862,8 @@ func New(path string) (*Storage, error) {
 }
 
 func (s *Storage) Init(ctx context.Context) error {
-
-	q := `
-		CREATE TABLE IF NOT EXISTS user (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name VARCHAR(255) NOT NULL,
-		    chat_id INTEGER NOT NULL,
-		    
-		    UNIQUE(name, chat_id)
-		);
-		
-		CREATE TABLE IF NOT EXISTS game (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			external_url VARCHAR(500) NULL,
-			source VARCHAR(255) NOT NULL,
-			name VARCHAR(255) NOT NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			
-			UNIQUE(source, external_url, name)
-		);
-		
-		CREATE TABLE IF NOT EXISTS wishlist (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER NOT NULL,
-			game_id INTEGER NOT NULL,
-			notification_date DATETIME NULL,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			notified_at DATETIME NULL,
-			
-			FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
-			FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE,
-			
-			UNIQUE(user_id, game_id)
-		);
-	`
-
-	_, err := s.db.ExecContext(ctx, q)
-	if err != nil {
-		return e.Wrap("can't create table", err)
+	if err := migrate.Up(ctx, s.db, migrations); err != nil {
+		return e.Wrap("can't init database", err)
 	}
 
 	return nil
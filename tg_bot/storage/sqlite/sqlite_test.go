@@ -0,0 +1,219 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"tg_game_wishlist/storage"
+	"tg_game_wishlist/storage/storagetest"
+	"time"
+)
+
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("can't open storage: %v", err)
+	}
+	t.Cleanup(func() { _ = s.db.Close() })
+
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("can't init storage: %v", err)
+	}
+
+	return s
+}
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t)
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	storagetest.RunStateStore(t, func(t *testing.T) storage.StateStore {
+		return newTestStorage(t)
+	})
+}
+
+func TestShareStore(t *testing.T) {
+	storagetest.RunShareStore(t, func(t *testing.T) storage.ShareStore {
+		return newTestStorage(t)
+	})
+}
+
+func TestCacheStore(t *testing.T) {
+	storagetest.RunCacheStore(t, func(t *testing.T) storage.CacheStore {
+		return newTestStorage(t)
+	})
+}
+
+func TestUpdateStore(t *testing.T) {
+	storagetest.RunUpdateStore(t, func(t *testing.T) storage.UpdateStore {
+		return newTestStorage(t)
+	})
+}
+
+// TestRemovePlatforms платформы удалённой записи не видны через storage.Storage, поэтому проверяются в таблице
+func TestRemovePlatforms(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	w := &storage.Wishlist{
+		User:      &storage.User{TelegramId: 1, Name: "user", ChatId: 1},
+		Game:      &storage.Game{Name: "doom", Source: storage.Igdb},
+		Platforms: []storage.Platform{{Id: 6, Name: "PC"}, {Id: 167, Name: "PS5"}},
+	}
+	if err := s.Add(ctx, w); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := s.Remove(ctx, w.Id); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM wishlist_platform`).Scan(&count); err != nil {
+		t.Fatalf("can't count platforms: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d platforms left after Remove, want 0", count)
+	}
+}
+
+// TestAddRollback запись не должна остаться в списке, если её платформы не добавились
+func TestAddRollback(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStorage(t)
+
+	//Повтор платформы нарушает UNIQUE(wishlist_id, platform_id)
+	w := &storage.Wishlist{
+		User:      &storage.User{TelegramId: 1, Name: "user", ChatId: 1},
+		Game:      &storage.Game{Name: "doom", Source: storage.Igdb},
+		Platforms: []storage.Platform{{Id: 6, Name: "PC"}, {Id: 6, Name: "PC"}},
+	}
+	if err := s.Add(ctx, w); err == nil {
+		t.Fatal("Add with duplicate platforms succeeded, want error")
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM wishlist`).Scan(&count); err != nil {
+		t.Fatalf("can't count wishlist: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("%d wishlist rows left after failed Add, want 0", count)
+	}
+}
+
+// baselineSchema схема, которую создавала версия бота до появления миграций
+const baselineSchema = `
+	CREATE TABLE IF NOT EXISTS user (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name VARCHAR(255) NOT NULL,
+		chat_id INTEGER NOT NULL,
+
+		UNIQUE(name, chat_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS game (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		external_url VARCHAR(500) NULL,
+		source VARCHAR(255) NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+		UNIQUE(source, external_url, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS wishlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		game_id INTEGER NOT NULL,
+		notification_date DATETIME NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		notified_at DATETIME NULL,
+
+		FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE,
+		FOREIGN KEY (game_id) REFERENCES game(id) ON DELETE CASCADE,
+
+		UNIQUE(user_id, game_id)
+	);
+`
+
+// TestInitBaseline Init должен обновить базу, созданную до миграций, не потеряв списки
+func TestInitBaseline(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := New(filepath.Join(t.TempDir(), "storage.db"))
+	if err != nil {
+		t.Fatalf("can't open storage: %v", err)
+	}
+	t.Cleanup(func() { _ = s.db.Close() })
+
+	//Пользователь сменил username, и для его чата появилась вторая строка с тем же списком.
+	//В группе -200 он добавлял игры под новым username, а ещё там есть участник без личного чата с ботом
+	seed := baselineSchema + `
+		INSERT INTO user (id, name, chat_id) VALUES
+			(1, 'old_name', 100), (2, 'new_name', 100),
+			(3, 'new_name', -200), (4, 'stranger', -200);
+		INSERT INTO game (id, external_url, source, name) VALUES
+			(1, 'https://www.igdb.com/games/doom', 1, 'doom'),
+			(2, 'https://www.igdb.com/games/quake', 1, 'quake'),
+			(3, 'https://www.igdb.com/games/halo', 1, 'halo');
+		INSERT INTO wishlist (user_id, game_id, notification_date) VALUES
+			(1, 1, '2030-05-01 00:00:00+00:00'),
+			(2, 1, NULL),
+			(2, 2, NULL),
+			(3, 2, NULL),
+			(3, 3, NULL),
+			(4, 1, NULL);
+	`
+	if _, err := s.db.ExecContext(ctx, seed); err != nil {
+		t.Fatalf("can't seed baseline schema: %v", err)
+	}
+
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	u, err := s.GetUserByTelegramId(ctx, 100)
+	if err != nil {
+		t.Fatalf("GetUserByTelegramId: %v", err)
+	}
+	if u.Name != "new_name" || u.ChatId != 100 {
+		t.Fatalf("user = %+v, want latest name in chat 100", u)
+	}
+
+	all, err := s.GetAll(ctx, u)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(all) != 3 || all[0].Game.Name != "doom" || all[1].Game.Name != "halo" || all[2].Game.Name != "quake" {
+		t.Fatalf("GetAll = %+v, want doom, halo and quake", all)
+	}
+	//Дата выхода уже сохранённой игры берётся из даты уведомления
+	if want := time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC); !all[0].Game.ReleaseDate.Equal(want) {
+		t.Fatalf("release date = %s, want %s", all[0].Game.ReleaseDate, want)
+	}
+
+	//Игры участника, которого не найти по личному чату, остаются в списке группы
+	group, err := s.GetUserByTelegramId(ctx, -200)
+	if err != nil {
+		t.Fatalf("GetUserByTelegramId for group: %v", err)
+	}
+	if group.Name != "" || group.ChatId != -200 {
+		t.Fatalf("group = %+v, want unnamed list of chat -200", group)
+	}
+	groupAll, err := s.GetAll(ctx, group)
+	if err != nil {
+		t.Fatalf("GetAll for group: %v", err)
+	}
+	if len(groupAll) != 1 || groupAll[0].Game.Name != "doom" {
+		t.Fatalf("group GetAll = %+v, want doom", groupAll)
+	}
+
+	//Остальные миграции тоже применены
+	if err := s.SaveShare(ctx, &storage.Share{Token: "token", TelegramId: 100, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveShare after Init: %v", err)
+	}
+}
@@ -9,27 +9,73 @@ import (
 type Storage interface {
 	Add(ctx context.Context, w *Wishlist) error
 	IsExists(ctx context.Context, w *Wishlist) (bool, error)
-	GetUserByName(ctx context.Context, userName string) (*User, error)
+	GetUserByTelegramId(ctx context.Context, telegramId int) (*User, error)
 	GetAll(ctx context.Context, u *User) ([]Wishlist, error)
+	GetPage(ctx context.Context, u *User, limit int, offset int) ([]Wishlist, error)
+	Count(ctx context.Context, u *User) (int, error)
 	GetReleased(ctx context.Context, u *User) ([]Wishlist, error)
 	GetUnreleased(ctx context.Context, u *User) ([]Wishlist, error)
+	// Get возвращает запись списка по id или ErrNoWishlist
+	Get(ctx context.Context, wishlistId int) (*Wishlist, error)
 	Remove(ctx context.Context, wishListId int) error
 	GetToNotify(ctx context.Context) ([]Wishlist, error)
 	Notify(ctx context.Context, w *Wishlist) error
+	MigrateChat(ctx context.Context, oldChatId int, newChatId int) error
+}
+
+// StateStore хранит незавершённые диалоги пользователей, чтобы они переживали перезапуск бота
+type StateStore interface {
+	GetState(ctx context.Context, telegramId int) (*State, error)
+	SaveState(ctx context.Context, s *State) error
+	RemoveState(ctx context.Context, telegramId int) error
+}
+
+// ShareStore хранит ссылки, по которым список желаемого можно посмотреть без права изменения
+type ShareStore interface {
+	SaveShare(ctx context.Context, s *Share) error
+	GetShare(ctx context.Context, token string) (*Share, error)
+	// RemoveShares отзывает все ссылки владельца
+	RemoveShares(ctx context.Context, telegramId int) error
+}
+
+// CacheStore хранит ответы внешних API между перезапусками; устаревшие записи отбрасывает тот, кто читает
+type CacheStore interface {
+	GetCache(ctx context.Context, key string) (*CacheEntry, error)
+	SaveCache(ctx context.Context, entry *CacheEntry) error
+	// RemoveCacheBefore удаляет записи, устаревшие к before
+	RemoveCacheBefore(ctx context.Context, before time.Time) error
+}
+
+// UpdateStore хранит прогресс чтения обновлений Telegram и уже обработанные обновления
+type UpdateStore interface {
+	GetOffset(ctx context.Context) (int, error)
+	SaveOffset(ctx context.Context, offset int) error
+	IsProcessed(ctx context.Context, updateId int) (bool, error)
+	MarkProcessed(ctx context.Context, updateId int) error
+	// RemoveProcessedBefore удаляет отметки об обработке старше before; в режиме webhook offset нет,
+	// и по нему отметки не чистятся
+	RemoveProcessedBefore(ctx context.Context, before time.Time) error
 }
 
 var (
 	ErrNoWishlist = errors.New("no wishlist")
 	ErrNoUser     = errors.New("user doesn't exist")
+	ErrNoState    = errors.New("state doesn't exist")
+	ErrNoShare    = errors.New("share doesn't exist")
+	ErrNoCache    = errors.New("cache entry doesn't exist")
 )
 
 type Wishlist struct {
-	Id               int
-	User             *User
+	Id int
+	// User владелец списка: пользователь или групповой чат, в котором ведётся общий список
+	User *User
+	// AddedBy участник группы, добавивший игру; для личного списка nil
+	AddedBy          *User
 	Game             *Game
 	NotificationDate time.Time
 	AddedAt          time.Time
 	NotifiedAt       time.Time
+	Platforms        []Platform
 }
 
 type Source int
@@ -46,10 +92,41 @@ type Game struct {
 	Name        string
 	Source      Source
 	ExternalURL string
+	// ExternalId id игры в источнике, для добавленных вручную 0
+	ExternalId  int
+	ReleaseDate time.Time
+}
+
+// Platform платформа, выбранная пользователем для записи в списке желаемого
+type Platform struct {
+	Id          int
+	Name        string
+	ReleaseDate time.Time
 }
 
 type User struct {
-	Id     int
-	Name   string
-	ChatId int
+	Id         int
+	TelegramId int
+	Name       string
+	ChatId     int
+}
+
+type State struct {
+	TelegramId int
+	Step       string
+	GameName   string
+	CreatedAt  time.Time
+}
+
+type CacheEntry struct {
+	Key       string
+	Value     []byte
+	ExpiresAt time.Time
+}
+
+// Share ссылка на список желаемого владельца TelegramId (пользователя или группы)
+type Share struct {
+	Token      string
+	TelegramId int
+	CreatedAt  time.Time
 }
@@ -0,0 +1,73 @@
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// RunCacheStore запускает набор тестов для storage.CacheStore; newStore должен возвращать пустое хранилище
+func RunCacheStore(t *testing.T, newStore func(t *testing.T) storage.CacheStore) {
+	t.Run("SaveGet", func(t *testing.T) {
+		testCache(t, newStore(t))
+	})
+	t.Run("RemoveCacheBefore", func(t *testing.T) {
+		testRemoveCacheBefore(t, newStore(t))
+	})
+}
+
+func testCache(t *testing.T, s storage.CacheStore) {
+	ctx := context.Background()
+
+	if _, err := s.GetCache(ctx, "search:half-life"); !errors.Is(err, storage.ErrNoCache) {
+		t.Fatalf("GetCache for unknown key = %v, want ErrNoCache", err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	for _, value := range []string{`[{"Id":1}]`, `[{"Id":2}]`} {
+		entry := &storage.CacheEntry{Key: "search:half-life", Value: []byte(value), ExpiresAt: expiresAt}
+		if err := s.SaveCache(ctx, entry); err != nil {
+			t.Fatalf("SaveCache: %v", err)
+		}
+	}
+
+	//Повторное сохранение перезаписывает значение
+	entry, err := s.GetCache(ctx, "search:half-life")
+	if err != nil {
+		t.Fatalf("GetCache: %v", err)
+	}
+	if !bytes.Equal(entry.Value, []byte(`[{"Id":2}]`)) || !entry.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("GetCache = %s until %s, want last value until %s", entry.Value, entry.ExpiresAt, expiresAt)
+	}
+}
+
+func testRemoveCacheBefore(t *testing.T, s storage.CacheStore) {
+	ctx := context.Background()
+	now := time.Now()
+
+	//Время записи в другом часовом поясе не должно мешать сравнению
+	moscow := time.FixedZone("MSK", 3*60*60)
+	for key, expiresAt := range map[string]time.Time{
+		"expired": now.Add(-time.Hour).In(moscow),
+		"fresh":   now.Add(time.Hour),
+	} {
+		entry := &storage.CacheEntry{Key: key, Value: []byte("null"), ExpiresAt: expiresAt}
+		if err := s.SaveCache(ctx, entry); err != nil {
+			t.Fatalf("SaveCache(%s): %v", key, err)
+		}
+	}
+
+	if err := s.RemoveCacheBefore(ctx, now); err != nil {
+		t.Fatalf("RemoveCacheBefore: %v", err)
+	}
+
+	if _, err := s.GetCache(ctx, "expired"); !errors.Is(err, storage.ErrNoCache) {
+		t.Fatalf("GetCache(expired) = %v, want ErrNoCache", err)
+	}
+	if _, err := s.GetCache(ctx, "fresh"); err != nil {
+		t.Fatalf("GetCache(fresh): %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// RunShareStore запускает набор тестов для storage.ShareStore; newStore должен возвращать пустое хранилище
+func RunShareStore(t *testing.T, newStore func(t *testing.T) storage.ShareStore) {
+	t.Run("SaveGetRemove", func(t *testing.T) {
+		testShare(t, newStore(t))
+	})
+}
+
+func testShare(t *testing.T, s storage.ShareStore) {
+	ctx := context.Background()
+
+	if _, err := s.GetShare(ctx, "unknown"); !errors.Is(err, storage.ErrNoShare) {
+		t.Fatalf("GetShare for unknown token = %v, want ErrNoShare", err)
+	}
+
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, share := range []storage.Share{
+		{Token: "first", TelegramId: 1, CreatedAt: createdAt},
+		{Token: "second", TelegramId: 1, CreatedAt: createdAt},
+		{Token: "group", TelegramId: -100, CreatedAt: createdAt},
+	} {
+		if err := s.SaveShare(ctx, &share); err != nil {
+			t.Fatalf("SaveShare(%s): %v", share.Token, err)
+		}
+	}
+
+	share, err := s.GetShare(ctx, "second")
+	if err != nil {
+		t.Fatalf("GetShare: %v", err)
+	}
+	if share.TelegramId != 1 || !share.CreatedAt.Equal(createdAt) {
+		t.Fatalf("GetShare = %+v, want owner 1 created at %s", share, createdAt)
+	}
+
+	//Отзываются все ссылки владельца, чужие остаются
+	if err := s.RemoveShares(ctx, 1); err != nil {
+		t.Fatalf("RemoveShares: %v", err)
+	}
+	for _, token := range []string{"first", "second"} {
+		if _, err := s.GetShare(ctx, token); !errors.Is(err, storage.ErrNoShare) {
+			t.Fatalf("GetShare(%s) after RemoveShares = %v, want ErrNoShare", token, err)
+		}
+	}
+	if _, err := s.GetShare(ctx, "group"); err != nil {
+		t.Fatalf("GetShare(group) after RemoveShares: %v", err)
+	}
+}
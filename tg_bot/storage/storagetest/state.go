@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// RunStateStore запускает набор тестов для storage.StateStore; newStore должен возвращать пустое хранилище
+func RunStateStore(t *testing.T, newStore func(t *testing.T) storage.StateStore) {
+	t.Run("SaveGetRemove", func(t *testing.T) {
+		testState(t, newStore(t))
+	})
+}
+
+func testState(t *testing.T, s storage.StateStore) {
+	ctx := context.Background()
+
+	if _, err := s.GetState(ctx, 1); !errors.Is(err, storage.ErrNoState) {
+		t.Fatalf("GetState for unknown user = %v, want ErrNoState", err)
+	}
+
+	createdAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := s.SaveState(ctx, &storage.State{TelegramId: 1, Step: "step", GameName: "doom", CreatedAt: createdAt}); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	state, err := s.GetState(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetState: %v", err)
+	}
+	if state.Step != "step" || state.GameName != "doom" || !state.CreatedAt.Equal(createdAt) {
+		t.Fatalf("GetState = %+v, want step, doom created at %s", state, createdAt)
+	}
+
+	//Повторное сохранение перезаписывает состояние
+	if err := s.SaveState(ctx, &storage.State{TelegramId: 1, Step: "step", GameName: "quake", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+
+	state, err = s.GetState(ctx, 1)
+	if err != nil || state.GameName != "quake" {
+		t.Fatalf("GetState after overwrite = %+v, %v; want quake", state, err)
+	}
+
+	if _, err := s.GetState(ctx, 2); !errors.Is(err, storage.ErrNoState) {
+		t.Fatalf("GetState for another user = %v, want ErrNoState", err)
+	}
+
+	if err := s.RemoveState(ctx, 1); err != nil {
+		t.Fatalf("RemoveState: %v", err)
+	}
+
+	if _, err := s.GetState(ctx, 1); !errors.Is(err, storage.ErrNoState) {
+		t.Fatalf("GetState after RemoveState = %v, want ErrNoState", err)
+	}
+}
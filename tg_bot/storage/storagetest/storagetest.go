@@ -0,0 +1,590 @@
+// Package storagetest содержит общий набор тестов, который должна проходить любая реализация storage.Storage.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// Run запускает набор тестов; newStorage должен каждый раз возвращать пустое хранилище
+func Run(t *testing.T, newStorage func(t *testing.T) storage.Storage) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"IsExists", testIsExists},
+		{"AddDuplicate", testAddDuplicate},
+		{"User", testUser},
+		{"GetAll", testGetAll},
+		{"GetPage", testGetPage},
+		{"ExternalId", testExternalId},
+		{"AddedBy", testAddedBy},
+		{"MigrateChat", testMigrateChat},
+		{"MigrateChatMerge", testMigrateChatMerge},
+		{"Platforms", testPlatforms},
+		{"Get", testGet},
+		{"Remove", testRemove},
+		{"ReleasedUnreleased", testReleasedUnreleased},
+		{"GetToNotify", testGetToNotify},
+		{"Notify", testNotify},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+const day = 24 * time.Hour
+
+func user(telegramId int) *storage.User {
+	return &storage.User{
+		TelegramId: telegramId,
+		Name:       "user",
+		ChatId:     telegramId,
+	}
+}
+
+func game(name string) *storage.Game {
+	return &storage.Game{
+		Name:        name,
+		Source:      storage.Igdb,
+		ExternalURL: "https://www.igdb.com/games/" + name,
+	}
+}
+
+func mustAdd(t *testing.T, s storage.Storage, w *storage.Wishlist) {
+	t.Helper()
+
+	if err := s.Add(context.Background(), w); err != nil {
+		t.Fatalf("Add(%s): %v", w.Game.Name, err)
+	}
+}
+
+func mustUser(t *testing.T, s storage.Storage, telegramId int) *storage.User {
+	t.Helper()
+
+	u, err := s.GetUserByTelegramId(context.Background(), telegramId)
+	if err != nil {
+		t.Fatalf("GetUserByTelegramId(%d): %v", telegramId, err)
+	}
+
+	return u
+}
+
+func names(wishlist []storage.Wishlist) []string {
+	res := make([]string, 0, len(wishlist))
+	for _, w := range wishlist {
+		res = append(res, w.Game.Name)
+	}
+
+	return res
+}
+
+func assertNames(t *testing.T, what string, got []storage.Wishlist, want ...string) {
+	t.Helper()
+
+	gotNames := names(got)
+	if len(gotNames) != len(want) {
+		t.Fatalf("%s = %v, want %v", what, gotNames, want)
+	}
+	for i := range want {
+		if gotNames[i] != want[i] {
+			t.Fatalf("%s = %v, want %v", what, gotNames, want)
+		}
+	}
+}
+
+func testIsExists(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	w := &storage.Wishlist{User: user(1), Game: game("doom")}
+
+	exists, err := s.IsExists(ctx, w)
+	if err != nil || exists {
+		t.Fatalf("IsExists before Add = %v, %v; want false", exists, err)
+	}
+
+	mustAdd(t, s, w)
+
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: user(1), Game: game("doom")})
+	if err != nil || !exists {
+		t.Fatalf("IsExists for same user and game = %v, %v; want true", exists, err)
+	}
+
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: user(2), Game: game("doom")})
+	if err != nil || exists {
+		t.Fatalf("IsExists for another user = %v, %v; want false", exists, err)
+	}
+
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: user(1), Game: game("quake")})
+	if err != nil || exists {
+		t.Fatalf("IsExists for another game = %v, %v; want false", exists, err)
+	}
+
+	manual := game("doom")
+	manual.Source = storage.Manual
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: user(1), Game: manual})
+	if err != nil || exists {
+		t.Fatalf("IsExists for same name from another source = %v, %v; want false", exists, err)
+	}
+
+	//Переименованный пользователь остаётся тем же
+	renamed := user(1)
+	renamed.Name = "renamed"
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: renamed, Game: game("doom")})
+	if err != nil || !exists {
+		t.Fatalf("IsExists for renamed user = %v, %v; want true", exists, err)
+	}
+}
+
+func testAddDuplicate(t *testing.T, s storage.Storage) {
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom")})
+
+	if err := s.Add(context.Background(), &storage.Wishlist{User: user(1), Game: game("doom")}); err == nil {
+		t.Fatal("Add of duplicate wishlist succeeded, want error")
+	}
+
+	all, err := s.GetAll(context.Background(), mustUser(t, s, 1))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll", all, "doom")
+}
+
+func testUser(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	if _, err := s.GetUserByTelegramId(ctx, 1); !errors.Is(err, storage.ErrNoUser) {
+		t.Fatalf("GetUserByTelegramId for unknown user = %v, want ErrNoUser", err)
+	}
+
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom")})
+
+	renamed := &storage.User{TelegramId: 1, Name: "renamed", ChatId: 10}
+	mustAdd(t, s, &storage.Wishlist{User: renamed, Game: game("quake")})
+
+	u := mustUser(t, s, 1)
+	if u.Name != "renamed" || u.ChatId != 10 {
+		t.Fatalf("user after rename = %+v, want name %q and chat 10", u, "renamed")
+	}
+
+	all, err := s.GetAll(ctx, u)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll", all, "doom", "quake")
+}
+
+func testGetAll(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("quake")})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom")})
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: game("heretic")})
+
+	all, err := s.GetAll(ctx, mustUser(t, s, 1))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll", all, "doom", "quake")
+
+	for _, w := range all {
+		if w.User.TelegramId != 1 {
+			t.Fatalf("GetAll returned wishlist of user %d", w.User.TelegramId)
+		}
+		if w.Game.ExternalURL != game(w.Game.Name).ExternalURL {
+			t.Fatalf("GetAll returned external url %q", w.Game.ExternalURL)
+		}
+	}
+}
+
+func testGetPage(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	for _, name := range []string{"quake", "doom", "heretic", "hexen", "blood"} {
+		mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game(name)})
+	}
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: game("duke")})
+
+	u := mustUser(t, s, 1)
+
+	count, err := s.Count(ctx, u)
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if count != 5 {
+		t.Fatalf("Count = %d, want 5", count)
+	}
+
+	pages := []struct {
+		offset int
+		want   []string
+	}{
+		{0, []string{"blood", "doom"}},
+		{2, []string{"heretic", "hexen"}},
+		{4, []string{"quake"}},
+		{6, nil},
+	}
+
+	for _, page := range pages {
+		wishlist, err := s.GetPage(ctx, u, 2, page.offset)
+		if err != nil {
+			t.Fatalf("GetPage(offset %d): %v", page.offset, err)
+		}
+		assertNames(t, "GetPage", wishlist, page.want...)
+	}
+}
+
+func testExternalId(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	//Игра, добавленная до появления external id, получает его при следующем добавлении
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom")})
+
+	withId := game("doom")
+	withId.ExternalId = 7351
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: withId})
+
+	for _, telegramId := range []int{1, 2} {
+		all, err := s.GetAll(ctx, mustUser(t, s, telegramId))
+		if err != nil {
+			t.Fatalf("GetAll: %v", err)
+		}
+		assertNames(t, "GetAll", all, "doom")
+		if all[0].Game.ExternalId != 7351 {
+			t.Fatalf("ExternalId = %d, want 7351", all[0].Game.ExternalId)
+		}
+	}
+}
+
+// groupId id группового чата, у групп они отрицательные
+const groupId = -100
+
+func group(chatId int) *storage.User {
+	return &storage.User{
+		TelegramId: chatId,
+		ChatId:     chatId,
+	}
+}
+
+func testAddedBy(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustAdd(t, s, &storage.Wishlist{User: group(groupId), AddedBy: user(1), Game: game("doom")})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), AddedBy: user(1), Game: game("quake")})
+
+	all, err := s.GetAll(ctx, mustUser(t, s, groupId))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll(group)", all, "doom")
+	if all[0].AddedBy == nil || all[0].AddedBy.TelegramId != 1 || all[0].AddedBy.Name != "user" {
+		t.Fatalf("AddedBy = %+v, want user 1", all[0].AddedBy)
+	}
+	if all[0].User.ChatId != groupId {
+		t.Fatalf("User.ChatId = %d, want %d", all[0].User.ChatId, groupId)
+	}
+
+	//В своём списке пользователь не отмечается
+	all, err = s.GetAll(ctx, mustUser(t, s, 1))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll(user)", all, "quake")
+	if all[0].AddedBy != nil {
+		t.Fatalf("AddedBy = %+v, want nil", all[0].AddedBy)
+	}
+}
+
+func testMigrateChat(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const supergroupId = -1001
+
+	mustAdd(t, s, &storage.Wishlist{User: group(groupId), AddedBy: user(1), Game: game("doom")})
+	saveGroupShare(t, s)
+
+	if err := s.MigrateChat(ctx, groupId, supergroupId); err != nil {
+		t.Fatalf("MigrateChat: %v", err)
+	}
+
+	if _, err := s.GetUserByTelegramId(ctx, groupId); !errors.Is(err, storage.ErrNoUser) {
+		t.Fatalf("GetUserByTelegramId(old chat) error = %v, want ErrNoUser", err)
+	}
+	assertGroupShare(t, s, supergroupId)
+
+	all, err := s.GetAll(ctx, mustUser(t, s, supergroupId))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll(supergroup)", all, "doom")
+	if all[0].User.ChatId != supergroupId {
+		t.Fatalf("User.ChatId = %d, want %d", all[0].User.ChatId, supergroupId)
+	}
+
+	//Миграция неизвестного чата ничего не делает
+	if err := s.MigrateChat(ctx, -5, -6); err != nil {
+		t.Fatalf("MigrateChat(unknown): %v", err)
+	}
+}
+
+func testMigrateChatMerge(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	const supergroupId = -1001
+
+	mustAdd(t, s, &storage.Wishlist{User: group(groupId), AddedBy: user(1), Game: game("doom")})
+	mustAdd(t, s, &storage.Wishlist{User: group(groupId), AddedBy: user(1), Game: game("quake")})
+	mustAdd(t, s, &storage.Wishlist{User: group(supergroupId), AddedBy: user(2), Game: game("quake")})
+	saveGroupShare(t, s)
+
+	if err := s.MigrateChat(ctx, groupId, supergroupId); err != nil {
+		t.Fatalf("MigrateChat: %v", err)
+	}
+	assertGroupShare(t, s, supergroupId)
+
+	all, err := s.GetAll(ctx, mustUser(t, s, supergroupId))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll(supergroup)", all, "doom", "quake")
+	if _, err := s.GetUserByTelegramId(ctx, groupId); !errors.Is(err, storage.ErrNoUser) {
+		t.Fatalf("GetUserByTelegramId(old chat) error = %v, want ErrNoUser", err)
+	}
+}
+
+// saveGroupShare создаёт ссылку на список группы, если хранилище умеет их хранить
+func saveGroupShare(t *testing.T, s storage.Storage) {
+	shares, ok := s.(storage.ShareStore)
+	if !ok {
+		return
+	}
+
+	share := &storage.Share{Token: "group", TelegramId: groupId, CreatedAt: time.Now()}
+	if err := shares.SaveShare(context.Background(), share); err != nil {
+		t.Fatalf("SaveShare: %v", err)
+	}
+}
+
+func assertGroupShare(t *testing.T, s storage.Storage, telegramId int) {
+	shares, ok := s.(storage.ShareStore)
+	if !ok {
+		return
+	}
+
+	share, err := shares.GetShare(context.Background(), "group")
+	if err != nil {
+		t.Fatalf("GetShare: %v", err)
+	}
+	if share.TelegramId != telegramId {
+		t.Fatalf("share owner = %d, want %d", share.TelegramId, telegramId)
+	}
+}
+
+func testPlatforms(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	date := time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	mustAdd(t, s, &storage.Wishlist{
+		User:             user(1),
+		Game:             game("doom"),
+		NotificationDate: date,
+		Platforms: []storage.Platform{
+			{Id: 6, Name: "PC", ReleaseDate: date},
+			{Id: 167, Name: "PS5", ReleaseDate: date},
+		},
+	})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("quake")})
+
+	all, err := s.GetAll(ctx, mustUser(t, s, 1))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll", all, "doom", "quake")
+
+	platforms := all[0].Platforms
+	if len(platforms) != 2 || platforms[0].Name != "PC" || platforms[1].Name != "PS5" {
+		t.Fatalf("platforms = %+v, want PC and PS5", platforms)
+	}
+	if platforms[1].Id != 167 || !platforms[1].ReleaseDate.Equal(date) {
+		t.Fatalf("platform = %+v, want id 167 released %s", platforms[1], date)
+	}
+	if len(all[1].Platforms) != 0 {
+		t.Fatalf("platforms of wishlist without platforms = %+v, want none", all[1].Platforms)
+	}
+}
+
+func testGet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	w := &storage.Wishlist{User: group(groupId), AddedBy: user(1), Game: game("doom"), Platforms: []storage.Platform{{Id: 6, Name: "PC"}}}
+	mustAdd(t, s, w)
+
+	got, err := s.Get(ctx, w.Id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Id != w.Id || got.Game.Name != "doom" || got.User.TelegramId != groupId || len(got.Platforms) != 1 {
+		t.Fatalf("Get = %+v, want doom of group %d with one platform", got, groupId)
+	}
+	if got.AddedBy == nil || got.AddedBy.TelegramId != 1 {
+		t.Fatalf("Get AddedBy = %+v, want user 1", got.AddedBy)
+	}
+
+	if _, err := s.Get(ctx, w.Id+100); !errors.Is(err, storage.ErrNoWishlist) {
+		t.Fatalf("Get for unknown id = %v, want ErrNoWishlist", err)
+	}
+}
+
+func testRemove(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom"), Platforms: []storage.Platform{{Id: 6, Name: "PC"}}})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("quake")})
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: game("doom")})
+
+	u := mustUser(t, s, 1)
+	all, err := s.GetAll(ctx, u)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+
+	if err := s.Remove(ctx, all[0].Id); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	all, err = s.GetAll(ctx, u)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll after Remove", all, "quake")
+
+	exists, err := s.IsExists(ctx, &storage.Wishlist{User: user(1), Game: game("doom")})
+	if err != nil || exists {
+		t.Fatalf("IsExists after Remove = %v, %v; want false", exists, err)
+	}
+
+	//Запись другого пользователя с той же игрой не затрагивается
+	exists, err = s.IsExists(ctx, &storage.Wishlist{User: user(2), Game: game("doom")})
+	if err != nil || !exists {
+		t.Fatalf("IsExists for another user after Remove = %v, %v; want true", exists, err)
+	}
+
+	//Игру можно добавить повторно, платформы удалённой записи к ней не относятся
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom")})
+	all, err = s.GetAll(ctx, u)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	assertNames(t, "GetAll after adding again", all, "doom", "quake")
+	if len(all[0].Platforms) != 0 {
+		t.Fatalf("platforms after adding again = %+v, want none", all[0].Platforms)
+	}
+
+	if err := s.Remove(ctx, -1); err != nil {
+		t.Fatalf("Remove of unknown wishlist: %v", err)
+	}
+}
+
+func testReleasedUnreleased(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+
+	released := game("released")
+	released.ReleaseDate = now.Add(-2 * day)
+	today := game("today")
+	today.ReleaseDate = now
+	upcoming := game("upcoming")
+	upcoming.ReleaseDate = now.Add(2 * day)
+	noDate := game("no date")
+
+	for _, g := range []*storage.Game{released, today, upcoming, noDate} {
+		mustAdd(t, s, &storage.Wishlist{User: user(1), Game: g})
+	}
+
+	u := mustUser(t, s, 1)
+
+	got, err := s.GetReleased(ctx, u)
+	if err != nil {
+		t.Fatalf("GetReleased: %v", err)
+	}
+	assertNames(t, "GetReleased", got, "released", "today")
+
+	got, err = s.GetUnreleased(ctx, u)
+	if err != nil {
+		t.Fatalf("GetUnreleased: %v", err)
+	}
+	assertNames(t, "GetUnreleased", got, "upcoming", "no date")
+}
+
+func testGetToNotify(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	now := time.Now()
+
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("yesterday"), NotificationDate: now.Add(-day)})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("today"), NotificationDate: now})
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: game("today")})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("tomorrow"), NotificationDate: now.Add(day)})
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("no date")})
+
+	got, err := s.GetToNotify(ctx)
+	if err != nil {
+		t.Fatalf("GetToNotify: %v", err)
+	}
+	assertNames(t, "GetToNotify", sortByName(got), "today", "yesterday")
+
+	for _, w := range got {
+		if w.User.ChatId != 1 {
+			t.Fatalf("GetToNotify returned chat %d, want 1", w.User.ChatId)
+		}
+	}
+}
+
+func testNotify(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	mustAdd(t, s, &storage.Wishlist{User: user(1), Game: game("doom"), NotificationDate: time.Now()})
+	mustAdd(t, s, &storage.Wishlist{User: user(2), Game: game("doom"), NotificationDate: time.Now()})
+
+	got, err := s.GetToNotify(ctx)
+	if err != nil {
+		t.Fatalf("GetToNotify: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("GetToNotify returned %d wishlists, want 2", len(got))
+	}
+
+	notified := got[0]
+	for i := 0; i < 2; i++ {
+		if err := s.Notify(ctx, &notified); err != nil {
+			t.Fatalf("Notify #%d: %v", i+1, err)
+		}
+	}
+
+	got, err = s.GetToNotify(ctx)
+	if err != nil {
+		t.Fatalf("GetToNotify after Notify: %v", err)
+	}
+	if len(got) != 1 || got[0].Id == notified.Id {
+		t.Fatalf("GetToNotify after Notify returned %v, want only the other wishlist", names(got))
+	}
+
+	all, err := s.GetAll(ctx, mustUser(t, s, notified.User.TelegramId))
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if len(all) != 1 || all[0].NotifiedAt.IsZero() {
+		t.Fatalf("notified wishlist has no NotifiedAt")
+	}
+}
+
+func sortByName(wishlist []storage.Wishlist) []storage.Wishlist {
+	res := append([]storage.Wishlist(nil), wishlist...)
+	sort.Slice(res, func(i, j int) bool { return res[i].Game.Name < res[j].Game.Name })
+
+	return res
+}
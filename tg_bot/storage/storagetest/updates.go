@@ -0,0 +1,100 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"tg_game_wishlist/storage"
+	"time"
+)
+
+// RunUpdateStore запускает набор тестов для storage.UpdateStore; newStore должен возвращать пустое хранилище
+func RunUpdateStore(t *testing.T, newStore func(t *testing.T) storage.UpdateStore) {
+	t.Run("Offset", func(t *testing.T) {
+		testOffset(t, newStore(t))
+	})
+	t.Run("Processed", func(t *testing.T) {
+		testProcessed(t, newStore(t))
+	})
+	t.Run("RemoveProcessedBefore", func(t *testing.T) {
+		testRemoveProcessedBefore(t, newStore(t))
+	})
+}
+
+func testOffset(t *testing.T, s storage.UpdateStore) {
+	ctx := context.Background()
+
+	offset, err := s.GetOffset(ctx)
+	if err != nil || offset != 0 {
+		t.Fatalf("GetOffset of empty store = %d, %v; want 0", offset, err)
+	}
+
+	for _, want := range []int{100, 150} {
+		if err := s.SaveOffset(ctx, want); err != nil {
+			t.Fatalf("SaveOffset(%d): %v", want, err)
+		}
+
+		offset, err = s.GetOffset(ctx)
+		if err != nil || offset != want {
+			t.Fatalf("GetOffset = %d, %v; want %d", offset, err, want)
+		}
+	}
+}
+
+func testProcessed(t *testing.T, s storage.UpdateStore) {
+	ctx := context.Background()
+
+	processed, err := s.IsProcessed(ctx, 10)
+	if err != nil || processed {
+		t.Fatalf("IsProcessed before MarkProcessed = %v, %v; want false", processed, err)
+	}
+
+	//Повторная отметка не считается ошибкой
+	for i := 0; i < 2; i++ {
+		if err := s.MarkProcessed(ctx, 10); err != nil {
+			t.Fatalf("MarkProcessed #%d: %v", i+1, err)
+		}
+	}
+	if err := s.MarkProcessed(ctx, 11); err != nil {
+		t.Fatalf("MarkProcessed: %v", err)
+	}
+
+	processed, err = s.IsProcessed(ctx, 10)
+	if err != nil || !processed {
+		t.Fatalf("IsProcessed after MarkProcessed = %v, %v; want true", processed, err)
+	}
+
+	//Сохранение offset удаляет обновления, которые Telegram больше не пришлёт
+	if err := s.SaveOffset(ctx, 11); err != nil {
+		t.Fatalf("SaveOffset: %v", err)
+	}
+
+	processed, err = s.IsProcessed(ctx, 11)
+	if err != nil || !processed {
+		t.Fatalf("IsProcessed for update at offset = %v, %v; want true", processed, err)
+	}
+}
+
+func testRemoveProcessedBefore(t *testing.T, s storage.UpdateStore) {
+	ctx := context.Background()
+
+	if err := s.MarkProcessed(ctx, 10); err != nil {
+		t.Fatalf("MarkProcessed: %v", err)
+	}
+
+	//Свежие отметки остаются
+	if err := s.RemoveProcessedBefore(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("RemoveProcessedBefore: %v", err)
+	}
+	processed, err := s.IsProcessed(ctx, 10)
+	if err != nil || !processed {
+		t.Fatalf("IsProcessed after removing older updates = %v, %v; want true", processed, err)
+	}
+
+	if err := s.RemoveProcessedBefore(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RemoveProcessedBefore: %v", err)
+	}
+	processed, err = s.IsProcessed(ctx, 10)
+	if err != nil || processed {
+		t.Fatalf("IsProcessed after removing all updates = %v, %v; want false", processed, err)
+	}
+}